@@ -0,0 +1,286 @@
+// Command ssm-tester runs suite files against an AWS account without go test, eg. for smoke checks after a change.
+//
+// Usage:
+//
+//	ssm-tester run [-profile name] [-region name] [-run regexp] [-junit report.xml] [-json report.json] [-git-sha sha] [-v] suite.yaml
+//	ssm-tester list-targets [-profile name] [-region name] suite.yaml
+//	ssm-tester validate [-run regexp] suite.yaml
+//
+// run runs the tests of the suite and reports each as PASS or FAIL, with the status and output of the test command on
+// each instance of a failed test. It optionally writes the results as JUnit XML for CI, and as JSON with the account,
+// region and git commit of the run, to keep as evidence of what was verified. list-targets reports the running instances each target of the suite resolves to.
+// validate checks the suite can be run, without calling AWS.
+//
+// The exit code is 0 if every test passed, 1 if any test failed, and 2 if the suite could not be loaded or run.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/ankitwal/ssm-tester/tester"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	exitPassed = 0
+	exitFailed = 1
+	exitError  = 2
+)
+
+const usage = `Usage:
+  ssm-tester run [-profile name] [-region name] [-run regexp] [-junit report.xml] [-json report.json] [-git-sha sha] [-v] suite.yaml
+  ssm-tester list-targets [-profile name] [-region name] suite.yaml
+  ssm-tester validate [-run regexp] suite.yaml
+`
+
+type ssmClient interface {
+	SendCommand(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error)
+	ListCommandInvocations(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error)
+}
+
+type ec2Client interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+}
+
+type stsClient interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+type awsClients struct {
+	ssm    ssmClient
+	ec2    ec2Client
+	sts    stsClient
+	region string // the region the clients are configured for
+}
+
+// newClients returns the AWS clients for the profile and region, the defaults of the environment are used if empty.
+// It is a variable so that it can be replaced in tests.
+var newClients = func(profile string, region string) (awsClients, error) {
+	var options []func(*config.LoadOptions) error
+	if profile != "" {
+		options = append(options, config.WithSharedConfigProfile(profile))
+	}
+	if region != "" {
+		options = append(options, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), options...)
+	if err != nil {
+		return awsClients{}, err
+	}
+	return awsClients{
+		ssm:    ssm.NewFromConfig(cfg),
+		ec2:    ec2.NewFromConfig(cfg),
+		sts:    sts.NewFromConfig(cfg),
+		region: cfg.Region,
+	}, nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the subcommand in args and returns the exit code.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	flags := flag.NewFlagSet("ssm-tester "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	// each subcommand registers only the flags it supports, so that the others are rejected
+	var profile, region, pattern, junitPath, jsonPath, gitSha string
+	var verbose bool
+	awsFlags := func() {
+		flags.StringVar(&profile, "profile", "", "the AWS shared config profile to use, the default profile if not set")
+		flags.StringVar(&region, "region", "", "the AWS region to use, the region of the profile if not set")
+	}
+	runFlag := func() {
+		flags.StringVar(&pattern, "run", "", "run only the tests whose name matches the regular expression")
+	}
+
+	var command func(suite tester.Suite) int
+	switch args[0] {
+	case "run":
+		awsFlags()
+		runFlag()
+		flags.StringVar(&junitPath, "junit", "", "write the results as JUnit XML to the file")
+		flags.StringVar(&jsonPath, "json", "", "write the results as JSON to the file")
+		flags.StringVar(&gitSha, "git-sha", "", "the git commit under test, for the JSON results")
+		flags.BoolVar(&verbose, "v", false, "log each command sent and each poll for its results")
+		command = func(suite tester.Suite) int {
+			clients, err := newClients(profile, region)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return exitError
+			}
+			reporters := reporters{junitPath: junitPath, jsonPath: jsonPath, metadata: tester.ReportMetadata{Name: flags.Arg(0)}}
+			// the account is only looked up for the JSON results, so that other runs do not need sts:GetCallerIdentity
+			if jsonPath != "" {
+				if reporters.metadata, err = runMetadata(clients, flags.Arg(0), gitSha); err != nil {
+					fmt.Fprintln(stderr, err)
+					return exitError
+				}
+			}
+			level := tester.LevelWarn
+			if verbose {
+				level = tester.LevelDebug
+			}
+			return runSuite(clients.ssm, suite.WithLogger(tester.NewWriterLogger(stderr, level)), reporters, stdout, stderr)
+		}
+	case "list-targets":
+		awsFlags()
+		command = func(suite tester.Suite) int {
+			clients, err := newClients(profile, region)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return exitError
+			}
+			return listTargets(clients.ec2, suite, stdout, stderr)
+		}
+	case "validate":
+		runFlag()
+		command = func(suite tester.Suite) int {
+			fmt.Fprintf(stdout, "%d tests, %d targets\n", len(suite.Tests), len(suite.Targets))
+			return exitPassed
+		}
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n%s", args[0], usage)
+		return exitError
+	}
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	suite, err := tester.LoadSuite(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", flags.Arg(0), err)
+		return exitError
+	}
+	if suite, err = suite.Filter(pattern); err != nil {
+		fmt.Fprintf(stderr, "-run: %v\n", err)
+		return exitError
+	}
+	if len(suite.Tests) == 0 {
+		fmt.Fprintf(stderr, "no tests match %q\n", pattern)
+		return exitError
+	}
+	return command(suite)
+}
+
+// runMetadata returns the metadata of a run of the suite file, the account is looked up as the caller of the clients.
+func runMetadata(clients awsClients, name string, gitSha string) (tester.ReportMetadata, error) {
+	metadata := tester.ReportMetadata{Name: name, Region: clients.region, Time: time.Now().UTC(), GitSha: gitSha}
+	identity, err := clients.sts.GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return metadata, err
+	}
+	if identity.Account != nil {
+		metadata.Account = *identity.Account
+	}
+	return metadata, nil
+}
+
+// reporters are the files to write the results of a run to, in addition to the standard output.
+type reporters struct {
+	metadata  tester.ReportMetadata // the metadata of the run
+	junitPath string                // the file to write JUnit XML to, not written if empty
+	jsonPath  string                // the file to write JSON to, not written if empty
+}
+
+func (r reporters) write(result tester.SuiteResult) error {
+	if r.junitPath != "" {
+		err := writeFile(r.junitPath, func(w io.Writer) error {
+			return tester.WriteJUnitReport(w, r.metadata.Name, result)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if r.jsonPath != "" {
+		return writeFile(r.jsonPath, func(w io.Writer) error {
+			return tester.WriteJsonReport(w, r.metadata, result)
+		})
+	}
+	return nil
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func runSuite(client ssmClient, suite tester.Suite, reporters reporters, stdout io.Writer, stderr io.Writer) int {
+	result, err := tester.RunSuiteE(client, suite)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	if err := reporters.write(result); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	failed := 0
+	for _, test := range result.Tests {
+		if test.Passed {
+			fmt.Fprintf(stdout, "PASS %s (%s)\n", test.Name, test.Duration.Round(100*time.Millisecond))
+			continue
+		}
+		failed++
+		fmt.Fprintf(stdout, "FAIL %s (%s)\n", test.Name, test.Duration.Round(100*time.Millisecond))
+		if test.Result.CommandId != "" {
+			fmt.Fprintf(stdout, "    command id: %s\n", test.Result.CommandId)
+		}
+		for _, invocation := range test.Result.Invocations {
+			fmt.Fprintf(stdout, "    %s %s\n", invocation.InstanceId, invocation.Status)
+			if output := strings.TrimSpace(invocation.Output); output != "" {
+				fmt.Fprintf(stdout, "        %s\n", strings.ReplaceAll(output, "\n", "\n        "))
+			}
+		}
+		fmt.Fprintf(stdout, "    %v\n", test.Err)
+	}
+	fmt.Fprintf(stdout, "%d passed, %d failed\n", len(result.Tests)-failed, failed)
+	if failed > 0 {
+		return exitFailed
+	}
+	return exitPassed
+}
+
+func listTargets(client ec2Client, suite tester.Suite, stdout io.Writer, stderr io.Writer) int {
+	targets, err := tester.DescribeSuiteTargetsE(client, suite)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	var names []string
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(stdout, "%s: %d instances\n", name, len(targets[name]))
+		for _, v := range targets[name] {
+			fmt.Fprintf(stdout, "    %s %s %s\n", v.InstanceId, v.PrivateIpAddress, v.Name)
+		}
+	}
+	return exitPassed
+}
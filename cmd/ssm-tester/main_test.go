@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSuite = `
+retry:
+  maxRetries: 0
+  waitBetweenRetries: 0s
+targets:
+  app:
+    tag:
+      Name: app
+tests:
+  - name: passing
+    target: app
+    shell:
+      command: "true"
+  - name: failing
+    target: app
+    shell:
+      command: "false"
+`
+
+type mockClient struct {
+	callerIdentityErr error // the error of GetCallerIdentity
+}
+
+func (m mockClient) SendCommand(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+	// the test command is used as the command id, so that its invocation can fail
+	return &ssm.SendCommandOutput{Command: &types.Command{CommandId: &params.Parameters["commands"][0]}}, nil
+}
+
+func (m mockClient) ListCommandInvocations(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error) {
+	status, output := types.CommandInvocationStatusSuccess, ""
+	if strings.Contains(*params.CommandId, "false") {
+		status, output = types.CommandInvocationStatusFailed, "FAIL: false"
+	}
+	instanceId := "i-1"
+	return &ssm.ListCommandInvocationsOutput{CommandInvocations: []types.CommandInvocation{{
+		InstanceId:     &instanceId,
+		Status:         status,
+		CommandPlugins: []types.CommandPlugin{{Output: &output}},
+	}}}, nil
+}
+
+func (m mockClient) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	instanceId, address := "i-1", "10.0.0.1"
+	return &ec2.DescribeInstancesOutput{Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{
+		{InstanceId: &instanceId, PrivateIpAddress: &address},
+	}}}}, nil
+}
+
+func (m mockClient) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	if m.callerIdentityErr != nil {
+		return nil, m.callerIdentityErr
+	}
+	account := "123456789012"
+	return &sts.GetCallerIdentityOutput{Account: &account}, nil
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssm-tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	suitePath := filepath.Join(dir, "suite.yaml")
+	if err := ioutil.WriteFile(suitePath, []byte(testSuite), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalidPath := filepath.Join(dir, "invalid.yaml")
+	if err := ioutil.WriteFile(invalidPath, []byte("tests: []"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newClients = func(profile string, region string) (awsClients, error) {
+		return awsClients{ssm: mockClient{}, ec2: mockClient{}, sts: mockClient{}, region: region}, nil
+	}
+
+	cases := []struct {
+		caseName       string
+		args           []string
+		expectedCode   int
+		expectedOutput []string
+	}{
+		{"no command", nil, exitError, nil},
+		{"unknown command", []string{"check", suitePath}, exitError, nil},
+		{"missing suite file", []string{"validate"}, exitError, nil},
+		{"invalid suite", []string{"validate", invalidPath}, exitError, nil},
+		{"valid suite", []string{"validate", suitePath}, exitPassed, []string{"2 tests, 1 targets"}},
+		{"invalid filter", []string{"validate", "-run", "(", suitePath}, exitError, nil},
+		{"flag of another command", []string{"list-targets", "-junit", "report.xml", suitePath}, exitError, nil},
+		{"AWS flags of validate", []string{"validate", "-json", "report.json", "-profile", "p", suitePath}, exitError, nil},
+		{"run flag of list-targets", []string{"list-targets", "-run", "^pass", suitePath}, exitError, nil},
+		{"filter matches no tests", []string{"run", "-run", "^none$", suitePath}, exitError, nil},
+		{"failing test", []string{"run", "-region", "ap-southeast-2", suitePath}, exitFailed, []string{
+			"PASS passing",
+			"FAIL failing",
+			"    i-1 Failed",
+			"        FAIL: false",
+			"1 passed, 1 failed",
+		}},
+		{"filtered to the passing test", []string{"run", "-run", "^pass", suitePath}, exitPassed, []string{"1 passed, 0 failed"}},
+		{"verbose", []string{"run", "-v", "-run", "^pass", suitePath}, exitPassed, nil},
+		{"reports", []string{"run", "-region", "ap-southeast-2", "-git-sha", "abc123",
+			"-junit", filepath.Join(dir, "report.xml"), "-json", filepath.Join(dir, "report.json"), suitePath}, exitFailed, nil},
+		{"list targets", []string{"list-targets", suitePath}, exitPassed, []string{"app: 1 instances", "    i-1 10.0.0.1"}},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if e, a := c.expectedCode, run(c.args, &stdout, &stderr); e != a {
+				t.Errorf("Expected exit code %d, but got %d with stderr %s", e, a, stderr.String())
+			}
+			for _, v := range c.expectedOutput {
+				if !strings.Contains(stdout.String(), v) {
+					t.Errorf("Expected the output to contain %q, but got \n%s", v, stdout.String())
+				}
+			}
+		})
+	}
+
+	report, err := ioutil.ReadFile(filepath.Join(dir, "report.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(report), `<testsuites name="`+suitePath+`" tests="2" failures="1" errors="0">`) {
+		t.Errorf("Expected a JUnit report of the run, but got \n%s", report)
+	}
+	report, err = ioutil.ReadFile(filepath.Join(dir, "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{`"account": "123456789012"`, `"region": "ap-southeast-2"`, `"gitSha": "abc123"`, `"status": "Failed"`} {
+		if !strings.Contains(string(report), v) {
+			t.Errorf("Expected the JSON report to contain %s, but got \n%s", v, report)
+		}
+	}
+}
+
+func TestRunWithoutCallerIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssm-tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	suitePath := filepath.Join(dir, "suite.yaml")
+	if err := ioutil.WriteFile(suitePath, []byte(testSuite), 0644); err != nil {
+		t.Fatal(err)
+	}
+	client := mockClient{callerIdentityErr: errors.New("not authorized to perform sts:GetCallerIdentity")}
+	newClients = func(profile string, region string) (awsClients, error) {
+		return awsClients{ssm: client, ec2: client, sts: client, region: region}, nil
+	}
+
+	cases := []struct {
+		caseName     string
+		args         []string
+		expectedCode int
+	}{
+		{"run", []string{"run", "-run", "^pass", "-junit", filepath.Join(dir, "report.xml"), suitePath}, exitPassed},
+		{"run with JSON results", []string{"run", "-run", "^pass", "-json", filepath.Join(dir, "report.json"), suitePath}, exitError},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if e, a := c.expectedCode, run(c.args, &stdout, &stderr); e != a {
+				t.Errorf("Expected exit code %d, but got %d with stderr %s", e, a, stderr.String())
+			}
+		})
+	}
+}
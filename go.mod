@@ -3,8 +3,12 @@ module github.com/ankitwal/ssm-tester
 go 1.16
 
 require (
+	github.com/aws/aws-sdk-go-v2 v1.7.1
 	github.com/aws/aws-sdk-go-v2/config v1.5.0
+	github.com/aws/aws-sdk-go-v2/service/ec2 v1.11.0
 	github.com/aws/aws-sdk-go-v2/service/ssm v1.8.0
+	github.com/aws/aws-sdk-go-v2/service/sts v1.6.0
 	github.com/aws/smithy-go v1.6.0
 	github.com/gruntwork-io/terratest v0.36.8
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -0,0 +1,362 @@
+package tester
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// ConnectivityMatrix describes a network segmentation policy as data: named groups of source instances, named groups of
+// destination instances, and the TCP ports that should be allowed from each source group to each destination group.
+// Every other combination of source group, destination group and port in the matrix is expected to be denied, eg.
+//
+//	matrix := tester.NewConnectivityMatrix().
+//		WithSource("web", tester.NewTagNameTarget("web")).
+//		WithSource("batch", tester.NewTagNameTarget("batch")).
+//		WithDestination("db", tester.NewTagNameTarget("db")).
+//		WithPorts(22).
+//		WithAllowed("web", "db", 5432)
+//
+// checks web can connect to db on 5432, and that batch cannot connect to db on 5432, and that neither can connect to db
+// on 22.
+type ConnectivityMatrix struct {
+	sources          []matrixGroup      // the groups of instances to connect from
+	destinations     []matrixGroup      // the groups of instances to connect to
+	ports            []int              // the ports to check between every source group and destination group
+	allowed          []connectivityRule // the connections that should be allowed
+	timeoutInSeconds int                // the time to wait for each connection
+}
+
+type matrixGroup struct {
+	name   string
+	target targetParamBuilder
+}
+
+type connectivityRule struct {
+	source      string
+	destination string
+	port        int
+}
+
+// ConnectivityCell is the outcome of the TCP checks from every instance of a source group to every instance of a
+// destination group on a port.
+type ConnectivityCell struct {
+	Source      string   // the name of the source group
+	Destination string   // the name of the destination group
+	Port        int      // the port connected to
+	Allowed     bool     // whether the connections are expected to be allowed
+	Mismatches  []string // the connections that did not behave as expected, as "<instance id> -> <ip>:<port> open|closed"
+}
+
+// Passed returns true if every connection of the cell behaved as expected.
+func (cc ConnectivityCell) Passed() bool {
+	return len(cc.Mismatches) == 0
+}
+
+func (cc ConnectivityCell) String() string {
+	expected := "deny"
+	if cc.Allowed {
+		expected = "allow"
+	}
+	return fmt.Sprintf("%s->%s:%d(%s)", cc.Source, cc.Destination, cc.Port, expected)
+}
+
+// ConnectivityMatrixResult are the cells of a ConnectivityMatrix, in the order of the source groups, destination groups
+// and ports.
+type ConnectivityMatrixResult struct {
+	Cells []ConnectivityCell
+}
+
+// Passed returns true if every cell of the matrix passed.
+func (cmr ConnectivityMatrixResult) Passed() bool {
+	for _, v := range cmr.Cells {
+		if !v.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// WithSource returns a copy of the ConnectivityMatrix with the instances of target as the source group name.
+func (cm ConnectivityMatrix) WithSource(name string, target targetParamBuilder) ConnectivityMatrix {
+	cm.sources = append(append([]matrixGroup{}, cm.sources...), matrixGroup{name: name, target: target})
+	return cm
+}
+
+// WithDestination returns a copy of the ConnectivityMatrix with the instances of target as the destination group name.
+// The instances are connected to on their primary private IP address.
+func (cm ConnectivityMatrix) WithDestination(name string, target targetParamBuilder) ConnectivityMatrix {
+	cm.destinations = append(append([]matrixGroup{}, cm.destinations...), matrixGroup{name: name, target: target})
+	return cm
+}
+
+// WithPorts returns a copy of the ConnectivityMatrix that checks ports between every source group and destination group,
+// in addition to the ports of the allowed connections.
+func (cm ConnectivityMatrix) WithPorts(ports ...int) ConnectivityMatrix {
+	cm.ports = append(append([]int{}, cm.ports...), ports...)
+	return cm
+}
+
+// WithAllowed returns a copy of the ConnectivityMatrix where connections from the source group to the destination group
+// on ports should be allowed. The ports are also checked between every other source group and destination group.
+func (cm ConnectivityMatrix) WithAllowed(source string, destination string, ports ...int) ConnectivityMatrix {
+	allowed := append([]connectivityRule{}, cm.allowed...)
+	for _, v := range ports {
+		allowed = append(allowed, connectivityRule{source: source, destination: destination, port: v})
+	}
+	cm.allowed = allowed
+	return cm
+}
+
+// WithTimeout returns a copy of the ConnectivityMatrix that waits timeoutInSeconds for each connection, before it is
+// considered denied.
+func (cm ConnectivityMatrix) WithTimeout(timeoutInSeconds int) ConnectivityMatrix {
+	cm.timeoutInSeconds = timeoutInSeconds
+	return cm
+}
+
+// allPorts returns the ports to check, sorted and without duplicates.
+func (cm ConnectivityMatrix) allPorts() []int {
+	seen := map[int]bool{}
+	var ports []int
+	for _, v := range cm.ports {
+		if !seen[v] {
+			seen[v] = true
+			ports = append(ports, v)
+		}
+	}
+	for _, v := range cm.allowed {
+		if !seen[v.port] {
+			seen[v.port] = true
+			ports = append(ports, v.port)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+func (cm ConnectivityMatrix) isAllowed(source string, destination string, port int) bool {
+	for _, v := range cm.allowed {
+		if v == (connectivityRule{source: source, destination: destination, port: port}) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewConnectivityMatrix is a constructor for ConnectivityMatrix type.
+// The groups and allowed connections are added with the With methods.
+func NewConnectivityMatrix() ConnectivityMatrix {
+	return ConnectivityMatrix{
+		timeoutInSeconds: 3,
+	}
+}
+
+// probesPerCommand is the number of connections tried by each command sent to a source group, so that the output of a
+// command is never truncated by SSM at 2500 characters. Each connection is reported in at most 33 characters, as
+// tcp/255.255.255.255/65535=closed.
+const probesPerCommand = 75
+
+type connectivityProbe struct {
+	address string
+	port    int
+}
+
+// connectivityProbeTestCase tries a TCP connection to each of the probes in parallel, and reports each as
+// tcp/<address>/<port>=open|closed. It always succeeds, the outcome is judged against the matrix by the tester.
+type connectivityProbeTestCase struct {
+	runShellScript
+	probes           []connectivityProbe
+	timeoutInSeconds int
+}
+
+func (cptc connectivityProbeTestCase) buildCommandParameters() map[string][]string {
+	commands := []string{
+		fmt.Sprintf(`probe() { if timeout %d bash -c "</dev/tcp/$1/$2" 2>/dev/null; then echo "tcp/$1/$2=open"; else echo "tcp/$1/$2=closed"; fi; }`,
+			cptc.timeoutInSeconds),
+	}
+	for _, v := range cptc.probes {
+		commands = append(commands, fmt.Sprintf("probe %s %d &", shellQuote(v.address), v.port))
+	}
+	return shellCommandParameters(append(commands, "wait"))
+}
+
+// probeTestCases returns the test cases that try every address on every port, each with at most probesPerCommand probes.
+func probeTestCases(addresses []string, ports []int, timeoutInSeconds int) []connectivityProbeTestCase {
+	var testCases []connectivityProbeTestCase
+	var probes []connectivityProbe
+	for _, address := range addresses {
+		for _, port := range ports {
+			probes = append(probes, connectivityProbe{address: address, port: port})
+			if len(probes) == probesPerCommand {
+				testCases = append(testCases, connectivityProbeTestCase{probes: probes, timeoutInSeconds: timeoutInSeconds})
+				probes = nil
+			}
+		}
+	}
+	if len(probes) > 0 {
+		testCases = append(testCases, connectivityProbeTestCase{probes: probes, timeoutInSeconds: timeoutInSeconds})
+	}
+	return testCases
+}
+
+// RunConnectivityMatrix runs the ConnectivityMatrix and reports each cell as a subtest named
+// "<source>-><destination>:<port>(allow|deny)", which fails if any of its connections do not behave as expected.
+// It fails the test if the instances of any group cannot be resolved, or the checks cannot be run.
+//
+// ssmClient is used to run the checks on the source instances, ec2Client to resolve the destination instances to their
+// private IP addresses.
+func RunConnectivityMatrix(t *testing.T, ssmClient commandSenderLister, ec2Client instanceDescriber, matrix ConnectivityMatrix,
+	retryConfig RetryConfig) ConnectivityMatrixResult {
+	result, err := RunConnectivityMatrixE(t, ssmClient, ec2Client, matrix, retryConfig)
+	if err != nil {
+		t.Error(err)
+		return result
+	}
+	for _, v := range result.Cells {
+		cell := v
+		t.Run(cell.String(), func(t *testing.T) {
+			for _, mismatch := range cell.Mismatches {
+				t.Error(mismatch)
+			}
+		})
+	}
+	return result
+}
+
+// RunConnectivityMatrixE is like RunConnectivityMatrix but returns the result and an error instead of failing the test.
+// It returns an error if the instances of any group cannot be resolved, or the checks cannot be run. Cells with
+// connections that do not behave as expected are not an error, and are reported by ConnectivityMatrixResult.Passed.
+//
+// It also returns an error if an allowed connection names a source or destination group that is not in the matrix.
+//
+// Commands are sent to each source group that try every destination address and port in parallel, as many as are
+// needed to keep the output of each command within the output limit of SSM.
+func RunConnectivityMatrixE(t *testing.T, ssmClient commandSenderLister, ec2Client instanceDescriber, matrix ConnectivityMatrix,
+	retryConfig RetryConfig) (ConnectivityMatrixResult, error) {
+	if err := matrix.validate(); err != nil {
+		return ConnectivityMatrixResult{}, err
+	}
+	ports := matrix.allPorts()
+	destinationAddresses := map[string][]string{}
+	// the addresses of all the destination groups, an instance may be in more than one group but is probed only once
+	var addresses []string
+	seen := map[string]bool{}
+	for _, destination := range matrix.destinations {
+		instances, err := DescribeTargetInstancesE(ec2Client, destination.target)
+		if err != nil {
+			return ConnectivityMatrixResult{}, fmt.Errorf("resolving destination %s: %w", destination.name, err)
+		}
+		for _, v := range instances {
+			if v.PrivateIpAddress != "" {
+				destinationAddresses[destination.name] = append(destinationAddresses[destination.name], v.PrivateIpAddress)
+				if !seen[v.PrivateIpAddress] {
+					seen[v.PrivateIpAddress] = true
+					addresses = append(addresses, v.PrivateIpAddress)
+				}
+			}
+		}
+		if len(destinationAddresses[destination.name]) == 0 {
+			return ConnectivityMatrixResult{}, noDestinationInstancesError{destination: destination.name}
+		}
+	}
+
+	var result ConnectivityMatrixResult
+	for _, source := range matrix.sources {
+		// the probe results of every command, by instance id, in the order the instances were first reported
+		var instanceIds []string
+		instanceValues := map[string]map[string]string{}
+		for _, testCase := range probeTestCases(addresses, ports, matrix.timeoutInSeconds) {
+			testResult, err := RunTestCaseForTargetWithResultsE(t, ssmClient, testCase, source.target, retryConfig)
+			if err != nil {
+				return result, fmt.Errorf("running checks from source %s: %w", source.name, err)
+			}
+			for _, invocation := range testResult.Invocations {
+				if _, found := instanceValues[invocation.InstanceId]; !found {
+					instanceIds = append(instanceIds, invocation.InstanceId)
+					instanceValues[invocation.InstanceId] = map[string]string{}
+				}
+				for k, v := range invocation.Values() {
+					instanceValues[invocation.InstanceId][k] = v
+				}
+			}
+		}
+		for _, destination := range matrix.destinations {
+			for _, port := range ports {
+				cell := ConnectivityCell{
+					Source:      source.name,
+					Destination: destination.name,
+					Port:        port,
+					Allowed:     matrix.isAllowed(source.name, destination.name, port),
+				}
+				for _, instanceId := range instanceIds {
+					values := instanceValues[instanceId]
+					for _, address := range destinationAddresses[destination.name] {
+						state, found := values[fmt.Sprintf("tcp/%s/%d", address, port)]
+						if !found {
+							state = "unchecked"
+						}
+						if (state == "open") != cell.Allowed || !found {
+							cell.Mismatches = append(cell.Mismatches, fmt.Sprintf("%s -> %s:%d %s", instanceId, address, port, state))
+						}
+					}
+				}
+				result.Cells = append(result.Cells, cell)
+			}
+		}
+	}
+	return result, nil
+}
+
+// validate returns an error if an allowed connection names a group that is not a source or destination of the matrix,
+// eg. a typo that would otherwise quietly expect the connection to be denied.
+func (cm ConnectivityMatrix) validate() error {
+	sources, destinations := map[string]bool{}, map[string]bool{}
+	for _, v := range cm.sources {
+		sources[v.name] = true
+	}
+	for _, v := range cm.destinations {
+		destinations[v.name] = true
+	}
+	for _, v := range cm.allowed {
+		if !sources[v.source] {
+			return unknownGroupError{kind: "source", name: v.source}
+		}
+		if !destinations[v.destination] {
+			return unknownGroupError{kind: "destination", name: v.destination}
+		}
+	}
+	return nil
+}
+
+type unknownGroupError struct {
+	kind string
+	name string
+}
+
+func (err unknownGroupError) Error() string {
+	return fmt.Sprintf("allowed connection names %s group %s, which is not in the matrix", err.kind, err.name)
+}
+
+type noDestinationInstancesError struct {
+	destination string
+}
+
+func (err noDestinationInstancesError) Error() string {
+	return fmt.Sprintf("no running instances with a private IP address found for destination %s", err.destination)
+}
+
+// String renders the result as a table of source groups against destination groups and ports, with each cell marked
+// allow or deny as expected, and FAIL if any of its connections did not behave as expected.
+func (cmr ConnectivityMatrixResult) String() string {
+	var builder strings.Builder
+	for _, v := range cmr.Cells {
+		outcome := "ok"
+		if !v.Passed() {
+			outcome = fmt.Sprintf("FAIL %s", strings.Join(v.Mismatches, ", "))
+		}
+		fmt.Fprintf(&builder, "%s %s\n", v, outcome)
+	}
+	return builder.String()
+}
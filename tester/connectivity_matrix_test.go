@@ -0,0 +1,198 @@
+package tester
+
+import (
+	"context"
+	"fmt"
+	"github.com/ankitwal/ssm-tester/tester/testerfake"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestConnectivityProbeTestCase(t *testing.T) {
+	testCases := probeTestCases([]string{"10.0.0.1", "10.0.0.2"}, []int{22, 5432}, 3)
+	if e, a := 1, len(testCases); e != a {
+		t.Fatalf("Expected %d test cases, but got %d", e, a)
+	}
+	testCase := testCases[0]
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			`probe() { if timeout 3 bash -c "</dev/tcp/$1/$2" 2>/dev/null; then echo "tcp/$1/$2=open"; else echo "tcp/$1/$2=closed"; fi; }`,
+			"probe '10.0.0.1' 22 &",
+			"probe '10.0.0.1' 5432 &",
+			"probe '10.0.0.2' 22 &",
+			"probe '10.0.0.2' 5432 &",
+			"wait",
+		},
+	}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestConnectivityMatrixPorts(t *testing.T) {
+	matrix := NewConnectivityMatrix().
+		WithPorts(22, 5432).
+		WithAllowed("web", "db", 5432, 6379).
+		WithAllowed("batch", "db", 5432)
+	if e, a := []int{22, 5432, 6379}, matrix.allPorts(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected ports %v, but got %v", e, a)
+	}
+	if !matrix.isAllowed("web", "db", 6379) {
+		t.Errorf("Expected web->db:6379 to be allowed")
+	}
+	if matrix.isAllowed("batch", "db", 6379) {
+		t.Errorf("Expected batch->db:6379 not to be allowed")
+	}
+}
+
+func TestRunConnectivityMatrixE(t *testing.T) {
+	// every source instance can reach the first db instance on 5432, and the web instance can also reach the second
+	outputs := map[string][]types.CommandInvocation{
+		"web": {
+			{InstanceId: stringPointer("i-web"), Status: types.CommandInvocationStatusSuccess, CommandPlugins: []types.CommandPlugin{{
+				Output: stringPointer("tcp/10.0.1.1/22=closed\ntcp/10.0.1.1/5432=open\ntcp/10.0.1.2/22=closed\ntcp/10.0.1.2/5432=open\n"),
+			}}},
+		},
+		"batch": {
+			{InstanceId: stringPointer("i-batch"), Status: types.CommandInvocationStatusSuccess, CommandPlugins: []types.CommandPlugin{{
+				Output: stringPointer("tcp/10.0.1.1/22=closed\ntcp/10.0.1.1/5432=open\ntcp/10.0.1.2/22=closed\n"),
+			}}},
+		},
+	}
+	ssmClient := &mockClient{
+		mockSendCommand: func(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+			return &ssm.SendCommandOutput{Command: &types.Command{CommandId: stringPointer(params.Targets[0].Values[0])}}, nil
+		},
+		mockListCommandInvocations: []func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error){
+			func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error) {
+				return &ssm.ListCommandInvocationsOutput{CommandInvocations: outputs[*params.CommandId]}, nil
+			},
+		},
+	}
+	ec2Client := mockInstanceDescriber{
+		mockDescribeInstances: func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			return &ec2.DescribeInstancesOutput{Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{
+				{InstanceId: stringPointer("i-db1"), PrivateIpAddress: stringPointer("10.0.1.1")},
+				{InstanceId: stringPointer("i-db2"), PrivateIpAddress: stringPointer("10.0.1.2")},
+			}}}}, nil
+		},
+	}
+	matrix := NewConnectivityMatrix().
+		WithSource("web", NewTagNameTarget("web")).
+		WithSource("batch", NewTagNameTarget("batch")).
+		WithDestination("db", NewTagNameTarget("db")).
+		WithPorts(22).
+		WithAllowed("web", "db", 5432)
+
+	result, err := RunConnectivityMatrixE(t, ssmClient, ec2Client, matrix, NewRetryConfig(0, 0))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	expected := ConnectivityMatrixResult{Cells: []ConnectivityCell{
+		{Source: "web", Destination: "db", Port: 22, Allowed: false},
+		{Source: "web", Destination: "db", Port: 5432, Allowed: true},
+		{Source: "batch", Destination: "db", Port: 22, Allowed: false},
+		{Source: "batch", Destination: "db", Port: 5432, Allowed: false, Mismatches: []string{
+			"i-batch -> 10.0.1.1:5432 open",
+			"i-batch -> 10.0.1.2:5432 unchecked",
+		}},
+	}}
+	if e, a := expected, result; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the result to be \n%v, but got \n%v", e, a)
+	}
+	if result.Passed() {
+		t.Errorf("Expected the result not to pass")
+	}
+}
+
+func TestRunConnectivityMatrixENoDestinationInstances(t *testing.T) {
+	ec2Client := mockInstanceDescriber{
+		mockDescribeInstances: func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			return &ec2.DescribeInstancesOutput{}, nil
+		},
+	}
+	matrix := NewConnectivityMatrix().
+		WithSource("web", NewTagNameTarget("web")).
+		WithDestination("db", NewTagNameTarget("db")).
+		WithAllowed("web", "db", 5432)
+	_, err := RunConnectivityMatrixE(t, &mockClient{}, ec2Client, matrix, NewRetryConfig(0, 0))
+	if e, a := (noDestinationInstancesError{destination: "db"}), err; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected error %v, but got %v", e, a)
+	}
+}
+
+func TestRunConnectivityMatrixELargeMatrix(t *testing.T) {
+	// 10 destination instances on 10 ports, more than 2500 characters of output if probed in a single command
+	var instances []ec2types.Instance
+	for i := 1; i <= 10; i++ {
+		instances = append(instances, ec2types.Instance{
+			InstanceId:       stringPointer(fmt.Sprintf("i-db%d", i)),
+			PrivateIpAddress: stringPointer(fmt.Sprintf("10.100.200.%d", i)),
+		})
+	}
+	ec2Client := mockInstanceDescriber{
+		mockDescribeInstances: func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			return &ec2.DescribeInstancesOutput{Reservations: []ec2types.Reservation{{Instances: instances}}}, nil
+		},
+	}
+	// the fake truncates the output of each command to 2500 characters, as SSM does
+	probePattern := regexp.MustCompile(`^probe '([^']*)' ([0-9]+) &$`)
+	ssmClient := testerfake.NewClient(testerfake.Instance{InstanceId: "i-web", Tags: map[string]string{"Name": "web"}}).
+		Respond(func(invocation testerfake.Invocation) (testerfake.Response, bool) {
+			var output strings.Builder
+			for _, v := range invocation.Parameters["commands"] {
+				if match := probePattern.FindStringSubmatch(v); match != nil {
+					state := "closed"
+					if match[2] == "5432" {
+						state = "open"
+					}
+					fmt.Fprintf(&output, "tcp/%s/%s=%s\n", match[1], match[2], state)
+				}
+			}
+			return testerfake.Response{Output: output.String()}, true
+		})
+	matrix := NewConnectivityMatrix().
+		WithSource("web", NewTagNameTarget("web")).
+		WithDestination("db", NewTagNameTarget("db")).
+		WithPorts(22, 80, 443, 3306, 6379, 8080, 8443, 9090, 9200).
+		WithAllowed("web", "db", 5432)
+
+	result, err := RunConnectivityMatrixE(t, ssmClient, ec2Client, matrix, NewRetryConfig(0, 0))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if !result.Passed() {
+		t.Errorf("Expected the result to pass, but got \n%v", result)
+	}
+	if e, a := 2, len(ssmClient.Commands()); e != a {
+		t.Errorf("Expected the probes to be split across %d commands, but got %d", e, a)
+	}
+}
+
+func TestRunConnectivityMatrixEUnknownGroup(t *testing.T) {
+	matrix := NewConnectivityMatrix().
+		WithSource("web", NewTagNameTarget("web")).
+		WithDestination("db", NewTagNameTarget("db"))
+	cases := []struct {
+		caseName      string
+		matrix        ConnectivityMatrix
+		expectedError error
+	}{
+		{"unknown source", matrix.WithAllowed("wbe", "db", 5432), unknownGroupError{kind: "source", name: "wbe"}},
+		{"unknown destination", matrix.WithAllowed("web", "dbs", 5432), unknownGroupError{kind: "destination", name: "dbs"}},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			_, err := RunConnectivityMatrixE(t, &mockClient{}, mockInstanceDescriber{}, c.matrix, NewRetryConfig(0, 0))
+			if e, a := c.expectedError, err; !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected error %v, but got %v", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package tester
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ContainerRuntime is a container runtime on a container host.
+type ContainerRuntime string
+
+const (
+	// Docker is the docker engine, as used by ECS container instances and older EKS worker nodes.
+	Docker ContainerRuntime = "docker"
+	// Containerd is containerd, as used by EKS worker nodes from kubernetes 1.24. Containers are inspected with crictl.
+	Containerd ContainerRuntime = "containerd"
+)
+
+// ContainerRuntimeTestCase configuration for a test that checks a container runtime is running on the target
+// instances, eg. ECS container instances or EKS worker nodes, and responds to its client.
+// The state of the runtime is reported as name=value pairs in the output, available with InvocationResult.Values.
+type ContainerRuntimeTestCase struct {
+	runShellScript
+	runtime ContainerRuntime // the container runtime
+}
+
+func (crtc ContainerRuntimeTestCase) buildCommandParameters() map[string][]string {
+	versionCommand := `docker info --format '{{.ServerVersion}}'`
+	if crtc.runtime == Containerd {
+		versionCommand = `ctr version | awk '/^Server:/ { server = 1 } server && $1 == "Version:" { print $2; exit }'`
+	}
+	runtime := shellQuote(string(crtc.runtime))
+	return shellCommandParameters(assertionScript(
+		fmt.Sprintf(`active=$(systemctl is-active %s 2>/dev/null); version=$(%s 2>/dev/null)`, runtime, versionCommand),
+		`echo "active=$active"; echo "version=$version"`,
+		fmt.Sprintf(`[ "$active" = active ] || fail 'unit' %s 'is' "$active" 'expected active'`, runtime),
+		fmt.Sprintf(`[ -n "$version" ] || fail %s 'is not responding to its client'`, runtime),
+	))
+}
+
+// NewContainerRuntimeTestCase is a constructor for ContainerRuntimeTestCase type.
+// runtime should be the container runtime of the target instances, Docker or Containerd.
+func NewContainerRuntimeTestCase(runtime ContainerRuntime) ContainerRuntimeTestCase {
+	return ContainerRuntimeTestCase{
+		runtime: runtime,
+	}
+}
+
+// ContainerTestCase configuration for a test that checks a container is running on the target instances, found by its
+// name, a label or its image, and optionally that the image it runs has an expected digest, eg. to prove the daemon set
+// pods or ECS daemon tasks of a node were started from the released image.
+// The test fails on an instance if no matching container is running, or if any matching container runs another image.
+// The ids and image digests of the matching containers are reported as name=value pairs in the output, available with
+// InvocationResult.Values.
+type ContainerTestCase struct {
+	runShellScript
+	runtime     ContainerRuntime // the container runtime, Docker if empty
+	name        string           // the exact name of the container, not filtered on if empty
+	label       string           // a label of the container as key=value, not filtered on if empty
+	image       string           // the image the container runs eg. nginx:1.21, not filtered on if empty
+	imageDigest string           // the digest the image of every matching container should have, not checked if empty
+}
+
+func (ctc ContainerTestCase) buildCommandParameters() map[string][]string {
+	var list, inspect string
+	if ctc.runtime == Containerd {
+		// crictl filters names by regular expression, and reports kubernetes container names without the pod prefix
+		list = "crictl ps -q --state Running"
+		if ctc.name != "" {
+			list += " --name " + shellQuote("^"+regexp.QuoteMeta(ctc.name)+"$")
+		}
+		if ctc.label != "" {
+			list += " --label " + shellQuote(ctc.label)
+		}
+		if ctc.image != "" {
+			list += " --image " + shellQuote(ctc.image)
+		}
+		inspect = `refs=$(crictl inspect -o go-template --template '{{.status.imageRef}} {{.status.image.image}}' "$id")`
+	} else {
+		// docker reports container names with a leading slash in older versions
+		list = "docker ps -q --filter status=running"
+		if ctc.name != "" {
+			list += " --filter " + shellQuote("name=^/?"+regexp.QuoteMeta(ctc.name)+"$")
+		}
+		if ctc.label != "" {
+			list += " --filter " + shellQuote("label="+ctc.label)
+		}
+		if ctc.image != "" {
+			list += " --filter " + shellQuote("ancestor="+ctc.image)
+		}
+		inspect = `refs=$(docker image inspect --format '{{.Id}} {{join .RepoDigests " "}}' "$(docker inspect --format '{{.Image}}' "$id")")`
+	}
+	assertions := []string{
+		fmt.Sprintf(`ids=$(%s); digests=""`, list),
+		fmt.Sprintf(`for id in $ids; do %s; digests="$digests $(echo " $refs" | grep -o '[@ ]sha256:[0-9a-f]*' | cut -c 2- | tr '\n' ' ')"; done`, inspect),
+		`echo "containers=$(echo $ids)"; echo "image_digests=$(echo $digests | tr ' ' '\n' | sort -u | xargs)"`,
+		fmt.Sprintf(`if [ -z "$ids" ]; then echo "FAIL: no running container found for "%s; exit 1; fi`, shellQuote(ctc.String())),
+	}
+	if ctc.imageDigest != "" {
+		assertions = append(assertions, fmt.Sprintf(`for id in $ids; do %s; case "$refs " in *@%s\ *|%s\ *) ;; *) fail 'container' "$id" 'runs image' "$refs" 'expected digest' %s ;; esac; done`,
+			inspect, shellQuote(ctc.imageDigest), shellQuote(ctc.imageDigest), shellQuote(ctc.imageDigest)))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+func (ctc ContainerTestCase) String() string {
+	var filters []string
+	if ctc.name != "" {
+		filters = append(filters, "name="+ctc.name)
+	}
+	if ctc.label != "" {
+		filters = append(filters, "label="+ctc.label)
+	}
+	if ctc.image != "" {
+		filters = append(filters, "image="+ctc.image)
+	}
+	return strings.Join(filters, " ")
+}
+
+// WithLabel returns a copy of the ContainerTestCase that only matches containers with the label key set to value, eg.
+// "io.kubernetes.pod.namespace", "kube-system".
+func (ctc ContainerTestCase) WithLabel(key string, value string) ContainerTestCase {
+	ctc.label = key + "=" + value
+	return ctc
+}
+
+// WithImage returns a copy of the ContainerTestCase that only matches containers running image eg. "amazon/aws-node:v1.9.0".
+func (ctc ContainerTestCase) WithImage(image string) ContainerTestCase {
+	ctc.image = image
+	return ctc
+}
+
+// WithImageDigest returns a copy of the ContainerTestCase that checks every matching container runs an image with digest
+// eg. "sha256:0f1e...". The digest is matched against both the repository digests and the id of the image.
+func (ctc ContainerTestCase) WithImageDigest(digest string) ContainerTestCase {
+	ctc.imageDigest = digest
+	return ctc
+}
+
+// WithRuntime returns a copy of the ContainerTestCase that finds containers with runtime instead of Docker.
+func (ctc ContainerTestCase) WithRuntime(runtime ContainerRuntime) ContainerTestCase {
+	ctc.runtime = runtime
+	return ctc
+}
+
+// NewContainerTestCase is a constructor for ContainerTestCase type.
+// name should be the exact name of the container eg. "ecs-agent", or empty to find containers only by label or image
+// with WithLabel or WithImage.
+func NewContainerTestCase(name string) ContainerTestCase {
+	return ContainerTestCase{
+		runtime: Docker,
+		name:    name,
+	}
+}
@@ -0,0 +1,69 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContainerRuntimeTestCase(t *testing.T) {
+	cases := []struct {
+		caseName                string
+		runtime                 ContainerRuntime
+		expectedVersionCommands string
+	}{
+		{
+			"docker",
+			Docker,
+			`active=$(systemctl is-active 'docker' 2>/dev/null); version=$(docker info --format '{{.ServerVersion}}' 2>/dev/null)`,
+		},
+		{
+			"containerd",
+			Containerd,
+			`active=$(systemctl is-active 'containerd' 2>/dev/null); version=$(ctr version | awk '/^Server:/ { server = 1 } server && $1 == "Version:" { print $2; exit }' 2>/dev/null)`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			assertCommandsContain(t, NewContainerRuntimeTestCase(c.runtime).buildCommandParameters()["commands"],
+				c.expectedVersionCommands,
+				`[ -n "$version" ] || fail '`+string(c.runtime)+`' 'is not responding to its client'`,
+			)
+		})
+	}
+}
+
+func TestContainerTestCase(t *testing.T) {
+	testCase := NewContainerTestCase("aws-node").
+		WithLabel("io.kubernetes.pod.namespace", "kube-system").
+		WithImageDigest("sha256:0123abcd")
+	inspect := `refs=$(docker image inspect --format '{{.Id}} {{join .RepoDigests " "}}' "$(docker inspect --format '{{.Image}}' "$id")")`
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`ids=$(docker ps -q --filter status=running --filter 'name=^/?aws-node$' --filter 'label=io.kubernetes.pod.namespace=kube-system'); digests=""`,
+			`for id in $ids; do ` + inspect + `; digests="$digests $(echo " $refs" | grep -o '[@ ]sha256:[0-9a-f]*' | cut -c 2- | tr '\n' ' ')"; done`,
+			`echo "containers=$(echo $ids)"; echo "image_digests=$(echo $digests | tr ' ' '\n' | sort -u | xargs)"`,
+			`if [ -z "$ids" ]; then echo "FAIL: no running container found for "'name=aws-node label=io.kubernetes.pod.namespace=kube-system'; exit 1; fi`,
+			`for id in $ids; do ` + inspect + `; case "$refs " in *@'sha256:0123abcd'\ *|'sha256:0123abcd'\ *) ;; *) fail 'container' "$id" 'runs image' "$refs" 'expected digest' 'sha256:0123abcd' ;; esac; done`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestContainerTestCaseWithContainerd(t *testing.T) {
+	testCase := NewContainerTestCase("").
+		WithRuntime(Containerd).
+		WithImage("602401143452.dkr.ecr.eu-west-1.amazonaws.com/amazon-k8s-cni:v1.9.0")
+	assertCommandsContain(t, testCase.buildCommandParameters()["commands"],
+		`ids=$(crictl ps -q --state Running --image '602401143452.dkr.ecr.eu-west-1.amazonaws.com/amazon-k8s-cni:v1.9.0'); digests=""`,
+		`if [ -z "$ids" ]; then echo "FAIL: no running container found for "'image=602401143452.dkr.ecr.eu-west-1.amazonaws.com/amazon-k8s-cni:v1.9.0'; exit 1; fi`,
+	)
+	// names are regular expressions for crictl
+	assertCommandsContain(t, NewContainerTestCase("kube-proxy.1").WithRuntime(Containerd).buildCommandParameters()["commands"],
+		`ids=$(crictl ps -q --state Running --name '^kube-proxy\.1$'); digests=""`,
+	)
+}
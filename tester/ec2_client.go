@@ -0,0 +1,18 @@
+package tester
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"testing"
+)
+
+// NewEC2ClientWithDefaultConfig initialises and returns and instance of AWS EC2 Service Client
+// with default config. It is used to resolve target instances to their private IP addresses.
+func NewEC2ClientWithDefaultConfig(t *testing.T) *ec2.Client {
+	config, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		t.Error(err)
+	}
+	return ec2.NewFromConfig(config)
+}
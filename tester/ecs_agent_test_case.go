@@ -0,0 +1,52 @@
+package tester
+
+// EcsAgentTestCase configuration for a test that checks the ECS agent on ECS container instances is running and has
+// registered the instance with a cluster, using the agent introspection API.
+// The registration is reported as name=value pairs in the output, available with InvocationResult.Values.
+type EcsAgentTestCase struct {
+	runShellScript
+	cluster        string // the name of the cluster the instance should be registered with, not checked if empty
+	agentConnected bool   // whether to also check ECS reports the agent as connected
+}
+
+func (eatc EcsAgentTestCase) buildCommandParameters() map[string][]string {
+	assertions := []string{
+		`metadata=$(curl -s --max-time 5 http://localhost:51678/v1/metadata)`,
+		`if [ -z "$metadata" ]; then echo "FAIL: ECS agent introspection API is not reachable"; exit 1; fi`,
+		`field() { echo "$metadata" | sed -n "s/.*\"$1\": *\"\([^\"]*\)\".*/\1/p"; }`,
+		`cluster=$(field Cluster); arn=$(field ContainerInstanceArn); version=$(field Version)`,
+		`echo "cluster=$cluster"; echo "container_instance_arn=$arn"; echo "version=$version"`,
+		`[ -n "$arn" ] || fail 'ECS agent has not registered the instance with a cluster'`,
+	}
+	if eatc.cluster != "" {
+		assertions = append(assertions, expectEqual("cluster", "$cluster", eatc.cluster))
+	}
+	if eatc.agentConnected {
+		assertions = append(assertions,
+			`connected=$(aws ecs describe-container-instances --region "$(echo "$arn" | cut -d : -f 4)" --cluster "$cluster" --container-instances "$arn" --query 'containerInstances[0].agentConnected' --output text 2>&1)`,
+			`echo "agent_connected=$connected"`,
+			`[ "$connected" = True ] || fail 'ECS agent is not connected:' "$connected"`,
+		)
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// WithCluster returns a copy of the EcsAgentTestCase that checks the instances are registered with the cluster name.
+func (eatc EcsAgentTestCase) WithCluster(name string) EcsAgentTestCase {
+	eatc.cluster = name
+	return eatc
+}
+
+// WithAgentConnected returns a copy of the EcsAgentTestCase that also checks ECS reports the agent of each instance as
+// connected, with the AWS CLI on the instance. This needs the instance role to allow ecs:DescribeContainerInstances,
+// which the AmazonEC2ContainerServiceforEC2Role managed policy does not.
+func (eatc EcsAgentTestCase) WithAgentConnected() EcsAgentTestCase {
+	eatc.agentConnected = true
+	return eatc
+}
+
+// NewEcsAgentTestCase is a constructor for EcsAgentTestCase type.
+// By default the test checks the agent has registered the instance with any cluster.
+func NewEcsAgentTestCase() EcsAgentTestCase {
+	return EcsAgentTestCase{}
+}
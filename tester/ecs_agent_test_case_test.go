@@ -0,0 +1,34 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEcsAgentTestCase(t *testing.T) {
+	testCase := NewEcsAgentTestCase().WithCluster("prod")
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`metadata=$(curl -s --max-time 5 http://localhost:51678/v1/metadata)`,
+			`if [ -z "$metadata" ]; then echo "FAIL: ECS agent introspection API is not reachable"; exit 1; fi`,
+			`field() { echo "$metadata" | sed -n "s/.*\"$1\": *\"\([^\"]*\)\".*/\1/p"; }`,
+			`cluster=$(field Cluster); arn=$(field ContainerInstanceArn); version=$(field Version)`,
+			`echo "cluster=$cluster"; echo "container_instance_arn=$arn"; echo "version=$version"`,
+			`[ -n "$arn" ] || fail 'ECS agent has not registered the instance with a cluster'`,
+			`[ "$cluster" = 'prod' ] || fail 'cluster is' "$cluster" 'expected' 'prod'`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestEcsAgentTestCaseWithAgentConnected(t *testing.T) {
+	assertCommandsContain(t, NewEcsAgentTestCase().WithAgentConnected().buildCommandParameters()["commands"],
+		`connected=$(aws ecs describe-container-instances --region "$(echo "$arn" | cut -d : -f 4)" --cluster "$cluster" --container-instances "$arn" --query 'containerInstances[0].agentConnected' --output text 2>&1)`,
+		`[ "$connected" = True ] || fail 'ECS agent is not connected:' "$connected"`,
+	)
+}
@@ -0,0 +1,138 @@
+package tester
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// EgressVerdict is the verdict of an EgressTestCase on whether the target instances can reach a hostname.
+type EgressVerdict string
+
+const (
+	// EgressAllowed means the destination responded to an HTTP request, through the proxy if one is configured.
+	EgressAllowed EgressVerdict = "allowed"
+	// EgressBlocked means the connection to the destination was refused, reset or timed out, or the proxy refused to
+	// tunnel to it.
+	EgressBlocked EgressVerdict = "blocked"
+	// EgressUnknown means the hostname or the proxy could not be resolved, or the certificate of the destination was not
+	// trusted eg. by a TLS intercepting firewall, so the test cannot tell if egress to the hostname is allowed or not.
+	EgressUnknown EgressVerdict = "unknown"
+)
+
+// EgressTestCase configuration for a test that checks whether the target instances can reach a hostname over HTTPS or
+// HTTP, optionally through an HTTP CONNECT proxy, eg. to regression test the domain allow-list of a Squid proxy or an
+// egress firewall that filters on the SNI or Host header.
+// The test passes on an instance if the verdict for the hostname matches the expected verdict. The verdict for each
+// instance is available from the TestResult with Verdicts.
+//
+// The test relies on the curl binary being installed on the target instances.
+type EgressTestCase struct {
+	runShellScript
+	hostname         string        // the hostname to reach, which is sent as the SNI and Host header
+	port             int           // the port to connect to, 443 for https and 80 for http if 0
+	plainHttp        bool          // whether to make a plain HTTP request rather than HTTPS
+	proxy            string        // the url of the HTTP CONNECT proxy to go through eg. http://squid.internal:3128, no proxy if empty
+	address          string        // the IP address to connect to instead of resolving hostname, resolved if empty
+	expected         EgressVerdict // the verdict the test expects
+	timeoutInSeconds int           // the time to wait for the request to complete
+}
+
+func (etc EgressTestCase) url() string {
+	scheme, port := "https", 443
+	if etc.plainHttp {
+		scheme, port = "http", 80
+	}
+	if etc.port != 0 {
+		port = etc.port
+	}
+	return fmt.Sprintf("%s://%s:%d/", scheme, etc.hostname, port)
+}
+
+func (etc EgressTestCase) buildCommandParameters() map[string][]string {
+	options := fmt.Sprintf("--max-time %d", etc.timeoutInSeconds)
+	if etc.proxy != "" {
+		// tunnel plain HTTP too, so that a request refused by the proxy is never mistaken for a response of the destination
+		options += " --proxy " + shellQuote(etc.proxy) + " --proxytunnel"
+	}
+	// the proxy resolves the hostname, so the address cannot be used
+	if etc.address != "" && etc.proxy == "" {
+		port := etc.port
+		if port == 0 {
+			port = 443
+			if etc.plainHttp {
+				port = 80
+			}
+		}
+		options += " --resolve " + shellQuote(etc.hostname+":"+strconv.Itoa(port)+":"+etc.address)
+	}
+	return shellCommandParameters(assertionScript(
+		fmt.Sprintf(`codes=$(curl -s -o /dev/null %s -w '%%{http_code} %%{http_connect}' %s)`, options, shellQuote(etc.url())),
+		`status=$?; http_code=${codes%% *}; proxy_code=${codes#* }`,
+		// older curl versions also report the response to a refused CONNECT as the http code
+		`case "$proxy_code" in 000|2??) proxy_refused=no ;; *) proxy_refused=yes ;; esac`,
+		// 5 and 6 are the curl exit codes for a proxy or host that could not be resolved, and 60 for an untrusted certificate
+		fmt.Sprintf(`if [ $status -eq 5 ] || [ $status -eq 6 ] || [ $status -eq 60 ]; then verdict=%s; elif [ "$http_code" != 000 ] && [ "$proxy_refused" = no ]; then verdict=%s; else verdict=%s; fi`,
+			EgressUnknown, EgressAllowed, EgressBlocked),
+		`echo "verdict=$verdict"; echo "curl_exit=$status"; echo "http_code=$http_code"; echo "proxy_code=$proxy_code"`,
+		fmt.Sprintf(`[ "$verdict" = %s ] || fail %s "$verdict" '- curl exit code' "$status" 'http code' "$http_code" 'proxy code' "$proxy_code"`,
+			etc.expected, shellQuote(fmt.Sprintf("%s expected %s, got", etc.hostname, etc.expected))),
+	))
+}
+
+// WithPort returns a copy of the EgressTestCase that connects to port instead of the default port of the scheme.
+func (etc EgressTestCase) WithPort(port int) EgressTestCase {
+	etc.port = port
+	return etc
+}
+
+// WithPlainHttp returns a copy of the EgressTestCase that makes a plain HTTP request, so that egress filters see the
+// hostname in the Host header rather than the SNI.
+func (etc EgressTestCase) WithPlainHttp() EgressTestCase {
+	etc.plainHttp = true
+	return etc
+}
+
+// WithProxy returns a copy of the EgressTestCase that goes through the HTTP CONNECT proxy at url eg.
+// "http://squid.internal:3128". The hostname is then resolved by the proxy.
+func (etc EgressTestCase) WithProxy(url string) EgressTestCase {
+	etc.proxy = url
+	return etc
+}
+
+// WithAddress returns a copy of the EgressTestCase that connects to the IP address, while still sending hostname as the
+// SNI and Host header, eg. to check a firewall blocks a domain regardless of the IP it is served from.
+// The address is not used when going through a proxy, which resolves the hostname itself.
+func (etc EgressTestCase) WithAddress(address string) EgressTestCase {
+	etc.address = address
+	return etc
+}
+
+// WithTimeout returns a copy of the EgressTestCase that waits timeoutInSeconds for the request to complete.
+func (etc EgressTestCase) WithTimeout(timeoutInSeconds int) EgressTestCase {
+	etc.timeoutInSeconds = timeoutInSeconds
+	return etc
+}
+
+// Verdicts returns the verdict for each instance in result, keyed by instance id.
+func (etc EgressTestCase) Verdicts(result TestResult) map[string]EgressVerdict {
+	verdicts := map[string]EgressVerdict{}
+	for _, v := range result.Invocations {
+		verdict := EgressVerdict(v.Values()["verdict"])
+		if verdict == "" {
+			verdict = EgressUnknown
+		}
+		verdicts[v.InstanceId] = verdict
+	}
+	return verdicts
+}
+
+// NewEgressTestCase is a constructor for EgressTestCase type.
+// hostname is the domain to make an HTTPS request to eg. "api.github.com".
+// expected is the verdict the test should check for, either EgressAllowed or EgressBlocked.
+func NewEgressTestCase(hostname string, expected EgressVerdict) EgressTestCase {
+	return EgressTestCase{
+		hostname:         hostname,
+		expected:         expected,
+		timeoutInSeconds: 5,
+	}
+}
@@ -0,0 +1,75 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEgressTestCase(t *testing.T) {
+	testCase := NewEgressTestCase("pypi.org", EgressBlocked).
+		WithProxy("http://squid.internal:3128")
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`codes=$(curl -s -o /dev/null --max-time 5 --proxy 'http://squid.internal:3128' --proxytunnel -w '%{http_code} %{http_connect}' 'https://pypi.org:443/')`,
+			`status=$?; http_code=${codes%% *}; proxy_code=${codes#* }`,
+			`case "$proxy_code" in 000|2??) proxy_refused=no ;; *) proxy_refused=yes ;; esac`,
+			`if [ $status -eq 5 ] || [ $status -eq 6 ] || [ $status -eq 60 ]; then verdict=unknown; elif [ "$http_code" != 000 ] && [ "$proxy_refused" = no ]; then verdict=allowed; else verdict=blocked; fi`,
+			`echo "verdict=$verdict"; echo "curl_exit=$status"; echo "http_code=$http_code"; echo "proxy_code=$proxy_code"`,
+			`[ "$verdict" = blocked ] || fail 'pypi.org expected blocked, got' "$verdict" '- curl exit code' "$status" 'http code' "$http_code" 'proxy code' "$proxy_code"`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestEgressTestCaseRequest(t *testing.T) {
+	cases := []struct {
+		caseName        string
+		testCase        EgressTestCase
+		expectedCommand string
+	}{
+		{
+			"https",
+			NewEgressTestCase("api.github.com", EgressAllowed),
+			`codes=$(curl -s -o /dev/null --max-time 5 -w '%{http_code} %{http_connect}' 'https://api.github.com:443/')`,
+		},
+		{
+			"plain http on port",
+			NewEgressTestCase("repo.internal", EgressAllowed).WithPlainHttp().WithPort(8080).WithTimeout(10),
+			`codes=$(curl -s -o /dev/null --max-time 10 -w '%{http_code} %{http_connect}' 'http://repo.internal:8080/')`,
+		},
+		{
+			"address",
+			NewEgressTestCase("blocked.example.com", EgressBlocked).WithAddress("93.184.216.34"),
+			`codes=$(curl -s -o /dev/null --max-time 5 --resolve 'blocked.example.com:443:93.184.216.34' -w '%{http_code} %{http_connect}' 'https://blocked.example.com:443/')`,
+		},
+		{
+			"address through a proxy",
+			NewEgressTestCase("blocked.example.com", EgressBlocked).WithAddress("93.184.216.34").WithProxy("http://squid.internal:3128"),
+			`codes=$(curl -s -o /dev/null --max-time 5 --proxy 'http://squid.internal:3128' --proxytunnel -w '%{http_code} %{http_connect}' 'https://blocked.example.com:443/')`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			assertCommandsContain(t, c.testCase.buildCommandParameters()["commands"], c.expectedCommand)
+		})
+	}
+}
+
+func TestEgressTestCaseVerdicts(t *testing.T) {
+	result := TestResult{
+		Invocations: []InvocationResult{
+			{InstanceId: "i-1", Output: "verdict=allowed\ncurl_exit=0\n"},
+			{InstanceId: "i-2", Output: "verdict=blocked\ncurl_exit=56\n"},
+			{InstanceId: "i-3", Output: ""},
+		},
+	}
+	expected := map[string]EgressVerdict{"i-1": EgressAllowed, "i-2": EgressBlocked, "i-3": EgressUnknown}
+	if e, a := expected, NewEgressTestCase("pypi.org", EgressBlocked).Verdicts(result); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the verdicts to be %v, but got %v", e, a)
+	}
+}
@@ -0,0 +1,147 @@
+package tester
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// EphemeralListenerTest configuration for an instance to instance connectivity test that does not need anything to be
+// listening on the destination instances. The tester starts a short-lived TCP listener on the destination instances,
+// tests the sources can connect to it, and then stops the listener.
+//
+// The listener is started with socat, python3 or python, whichever is installed first, and exits by itself after its
+// lifetime, so that it does not outlive the test even if it cannot be stopped.
+type EphemeralListenerTest struct {
+	source            targetParamBuilder // the instances to connect from
+	destination       targetParamBuilder // the instances to start the listener on
+	port              int                // the port to listen on
+	lifetimeInSeconds int                // the time after which the listener exits by itself
+	timeoutInSeconds  int                // the time to wait for each connection
+}
+
+// WithLifetime returns a copy of the EphemeralListenerTest where the listener exits by itself after lifetimeInSeconds.
+// It should be longer than the time to run the connection test, including polling for its results.
+func (elt EphemeralListenerTest) WithLifetime(lifetimeInSeconds int) EphemeralListenerTest {
+	elt.lifetimeInSeconds = lifetimeInSeconds
+	return elt
+}
+
+// WithTimeout returns a copy of the EphemeralListenerTest that waits timeoutInSeconds for each connection.
+func (elt EphemeralListenerTest) WithTimeout(timeoutInSeconds int) EphemeralListenerTest {
+	elt.timeoutInSeconds = timeoutInSeconds
+	return elt
+}
+
+// NewEphemeralListenerTest is a constructor for EphemeralListenerTest type.
+// source and destination are the instances to test connectivity between, on port. The port must not be in use on the
+// destination instances.
+func NewEphemeralListenerTest(source targetParamBuilder, destination targetParamBuilder, port int) EphemeralListenerTest {
+	return EphemeralListenerTest{
+		source:            source,
+		destination:       destination,
+		port:              port,
+		lifetimeInSeconds: 300,
+		timeoutInSeconds:  3,
+	}
+}
+
+func ephemeralListenerPidFile(port int) string {
+	return fmt.Sprintf("/tmp/ssm-tester-listener-%d.pid", port)
+}
+
+// startListenerTestCase starts a listener in the background, detached from the command so that the ssm agent does not
+// wait on it, and reports the primary private address of the instance.
+type startListenerTestCase struct {
+	runShellScript
+	port              int
+	lifetimeInSeconds int
+}
+
+func (sltc startListenerTestCase) buildCommandParameters() map[string][]string {
+	python := shellQuote(fmt.Sprintf(`import socket;s=socket.socket();s.setsockopt(socket.SOL_SOCKET,socket.SO_REUSEADDR,1);s.bind((str(),%d));s.listen(16);[s.accept()[0].close() for _ in iter(int,1)]`,
+		sltc.port))
+	pidFile := shellQuote(ephemeralListenerPidFile(sltc.port))
+	return shellCommandParameters(assertionScript(
+		fmt.Sprintf(`if command -v socat >/dev/null 2>&1; then set -- socat TCP-LISTEN:%d,reuseaddr,fork SYSTEM:true`, sltc.port),
+		fmt.Sprintf(`elif command -v python3 >/dev/null 2>&1; then set -- python3 -c %s`, python),
+		fmt.Sprintf(`elif command -v python >/dev/null 2>&1; then set -- python -c %s`, python),
+		`else echo "FAIL: none of socat, python3 or python is installed to start a listener"; exit 1; fi`,
+		fmt.Sprintf(`nohup setsid timeout %d "$@" >/dev/null 2>&1 </dev/null & pid=$!`, sltc.lifetimeInSeconds),
+		// only record a listener that is running, so that the listener of another test on the port is not stopped
+		fmt.Sprintf(`sleep 1; if kill -0 "$pid" 2>/dev/null; then echo "$pid" > %s; else fail 'listener exited, port %d may already be in use'; fi`, pidFile, sltc.port),
+		`echo "address=$(hostname -I | awk '{ print $1 }')"`,
+	))
+}
+
+// stopListenerTestCase stops the listener started by startListenerTestCase, if it is still running. timeout passes the
+// signal on to the listener.
+type stopListenerTestCase struct {
+	runShellScript
+	port int
+}
+
+func (sltc stopListenerTestCase) buildCommandParameters() map[string][]string {
+	pidFile := shellQuote(ephemeralListenerPidFile(sltc.port))
+	return shellCommandParameters([]string{
+		fmt.Sprintf(`if [ -f %s ]; then kill "$(cat %s)" 2>/dev/null; rm -f %s; fi`, pidFile, pidFile, pidFile),
+		"exit 0",
+	})
+}
+
+// connectTestCase checks a TCP connection can be made to each of the addresses on the port.
+type connectTestCase struct {
+	runShellScript
+	addresses        []string
+	port             int
+	timeoutInSeconds int
+}
+
+func (ctc connectTestCase) buildCommandParameters() map[string][]string {
+	var assertions []string
+	for _, v := range ctc.addresses {
+		assertions = append(assertions, fmt.Sprintf(`%s 2>/dev/null || fail 'cannot connect to' %s`,
+			tcpConnectionTestShellCommand(ctc.timeoutInSeconds, v, fmt.Sprint(ctc.port)), shellQuote(fmt.Sprintf("%s:%d", v, ctc.port))))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// RunEphemeralListenerTest runs the EphemeralListenerTest.
+// It fails the test if the listener cannot be started on any of the destination instances.
+// It fails the test if any one of the source instances cannot connect to any one of the destination instances.
+// The listener is stopped whether the test passes or fails.
+func RunEphemeralListenerTest(t *testing.T, client commandSenderLister, test EphemeralListenerTest, retryConfig RetryConfig) {
+	_, err := RunEphemeralListenerTestE(t, client, test, retryConfig)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// RunEphemeralListenerTestE is like RunEphemeralListenerTest but returns a bool and error.
+// It returns true and nil if the listener was started on all the destination instances, and all the source instances
+// could connect to it. It returns false and an error otherwise, including if the listener could not be stopped.
+func RunEphemeralListenerTestE(t *testing.T, client commandSenderLister, test EphemeralListenerTest, retryConfig RetryConfig) (passed bool, err error) {
+	// stop the listener on the way out, even if it was only started on some of the destination instances
+	defer func() {
+		_, stopErr := RunTestCaseForTargetE(t, client, stopListenerTestCase{port: test.port}, test.destination, retryConfig)
+		if stopErr != nil && err == nil {
+			passed, err = false, fmt.Errorf("stopping listener: %w", stopErr)
+		}
+	}()
+
+	started, err := RunTestCaseForTargetWithResultsE(t, client,
+		startListenerTestCase{port: test.port, lifetimeInSeconds: test.lifetimeInSeconds}, test.destination, retryConfig)
+	if err != nil {
+		return false, fmt.Errorf("starting listener: %w", err)
+	}
+	var addresses []string
+	for _, v := range started.Invocations {
+		address := strings.TrimSpace(v.Values()["address"])
+		if address == "" {
+			return false, fmt.Errorf("starting listener: no address reported by instance %s", v.InstanceId)
+		}
+		addresses = append(addresses, address)
+	}
+	return RunTestCaseForTargetE(t, client,
+		connectTestCase{addresses: addresses, port: test.port, timeoutInSeconds: test.timeoutInSeconds}, test.source, retryConfig)
+}
@@ -0,0 +1,131 @@
+package tester
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"reflect"
+	"testing"
+)
+
+func TestStartListenerTestCase(t *testing.T) {
+	assertCommandsContain(t, startListenerTestCase{port: 8080, lifetimeInSeconds: 120}.buildCommandParameters()["commands"],
+		`if command -v socat >/dev/null 2>&1; then set -- socat TCP-LISTEN:8080,reuseaddr,fork SYSTEM:true`,
+		`elif command -v python3 >/dev/null 2>&1; then set -- python3 -c 'import socket;s=socket.socket();s.setsockopt(socket.SOL_SOCKET,socket.SO_REUSEADDR,1);s.bind((str(),8080));s.listen(16);[s.accept()[0].close() for _ in iter(int,1)]'`,
+		`nohup setsid timeout 120 "$@" >/dev/null 2>&1 </dev/null & pid=$!`,
+		`sleep 1; if kill -0 "$pid" 2>/dev/null; then echo "$pid" > '/tmp/ssm-tester-listener-8080.pid'; else fail 'listener exited, port 8080 may already be in use'; fi`,
+		`echo "address=$(hostname -I | awk '{ print $1 }')"`,
+	)
+}
+
+func TestStopListenerTestCase(t *testing.T) {
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			`if [ -f '/tmp/ssm-tester-listener-8080.pid' ]; then kill "$(cat '/tmp/ssm-tester-listener-8080.pid')" 2>/dev/null; rm -f '/tmp/ssm-tester-listener-8080.pid'; fi`,
+			"exit 0",
+		},
+	}
+	if e, a := expectedCommandParameters, (stopListenerTestCase{port: 8080}).buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestConnectTestCase(t *testing.T) {
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`timeout 3 bash -c '</dev/tcp/10.0.1.1/8080' 2>/dev/null || fail 'cannot connect to' '10.0.1.1:8080'`,
+			`timeout 3 bash -c '</dev/tcp/10.0.1.2/8080' 2>/dev/null || fail 'cannot connect to' '10.0.1.2:8080'`,
+			"exit $rc",
+		},
+	}
+	testCase := connectTestCase{addresses: []string{"10.0.1.1", "10.0.1.2"}, port: 8080, timeoutInSeconds: 3}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestRunEphemeralListenerTestE(t *testing.T) {
+	cases := []struct {
+		caseName         string
+		startStatus      types.CommandInvocationStatus
+		connectStatus    types.CommandInvocationStatus
+		expected         bool
+		expectError      bool
+		expectedCommands []string
+	}{
+		{
+			"passes when the sources connect",
+			types.CommandInvocationStatusSuccess,
+			types.CommandInvocationStatusSuccess,
+			true,
+			false,
+			[]string{"start", "connect", "stop"},
+		},
+		{
+			"stops the listener when the sources cannot connect",
+			types.CommandInvocationStatusSuccess,
+			types.CommandInvocationStatusFailed,
+			false,
+			true,
+			[]string{"start", "connect", "stop"},
+		},
+		{
+			"stops the listener when it cannot be started",
+			types.CommandInvocationStatusFailed,
+			types.CommandInvocationStatusSuccess,
+			false,
+			true,
+			[]string{"start", "stop"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			var commands []string
+			client := &mockClient{
+				mockSendCommand: func(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+					command := "unknown"
+					for name, testCase := range map[string]commandParameterBuilder{
+						"start":   startListenerTestCase{port: 8080, lifetimeInSeconds: 300},
+						"connect": connectTestCase{addresses: []string{"10.0.1.1"}, port: 8080, timeoutInSeconds: 3},
+						"stop":    stopListenerTestCase{port: 8080},
+					} {
+						if reflect.DeepEqual(testCase.buildCommandParameters(), params.Parameters) {
+							command = name
+						}
+					}
+					commands = append(commands, command)
+					return &ssm.SendCommandOutput{Command: &types.Command{CommandId: stringPointer(command)}}, nil
+				},
+				mockListCommandInvocations: []func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error){
+					func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error) {
+						status, output := types.CommandInvocationStatusSuccess, ""
+						switch *params.CommandId {
+						case "start":
+							status, output = c.startStatus, "address=10.0.1.1\n"
+						case "connect":
+							status = c.connectStatus
+						}
+						return &ssm.ListCommandInvocationsOutput{CommandInvocations: []types.CommandInvocation{{
+							InstanceId:     stringPointer("i-1"),
+							Status:         status,
+							CommandPlugins: []types.CommandPlugin{{Output: stringPointer(output)}},
+						}}}, nil
+					},
+				},
+			}
+			test := NewEphemeralListenerTest(NewTagNameTarget("web"), NewTagNameTarget("api"), 8080)
+			passed, err := RunEphemeralListenerTestE(t, client, test, NewRetryConfig(0, 0))
+			if e, a := c.expected, passed; e != a {
+				t.Errorf("Expected %v, but got %v", e, a)
+			}
+			if e, a := c.expectError, err != nil; e != a {
+				t.Errorf("Expected an error %v, but got %v", e, err)
+			}
+			if e, a := c.expectedCommands, commands; !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the commands sent to be %v, but got %v", e, a)
+			}
+		})
+	}
+}
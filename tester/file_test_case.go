@@ -0,0 +1,147 @@
+package tester
+
+import "fmt"
+
+// FileType is the type of a path on a target instance, as checked by a FileTestCase.
+type FileType string
+
+const (
+	// RegularFile is a regular file.
+	RegularFile FileType = "file"
+	// Directory is a directory.
+	Directory FileType = "directory"
+	// Symlink is a symbolic link.
+	Symlink FileType = "symlink"
+)
+
+// FileTestCase configuration for a test that checks the state of a file or directory on the target instances, eg. to
+// validate that user-data or configuration management laid down the right files.
+// Each of the configured checks is made on every instance and the test fails on an instance if any of them do not hold,
+// with every mismatch reported in the output of the instance. The actual state of the path is reported as name=value
+// pairs in the output, available with InvocationResult.Values.
+//
+// The checks rely on GNU or busybox coreutils (stat, sha256sum) and grep, which are available on most linux distributions.
+type FileTestCase struct {
+	runShellScript
+	path           string   // the path of the file or directory
+	absent         bool     // check that the path does not exist
+	fileType       FileType // the expected type of the path, not checked if empty
+	owner          string   // the expected owner user name, not checked if empty
+	group          string   // the expected group name, not checked if empty
+	mode           string   // the expected octal access mode eg. 644, not checked if empty
+	minSize        int64    // the minimum size in bytes, not checked if negative
+	maxSize        int64    // the maximum size in bytes, not checked if negative
+	sha256         string   // the expected hex encoded sha256 checksum of the content, not checked if empty
+	contentPattern string   // extended regular expression some line of the content should match, not checked if empty
+}
+
+func (ftc FileTestCase) buildCommandParameters() map[string][]string {
+	path := shellQuote(ftc.path)
+	if ftc.absent {
+		return shellCommandParameters(assertionScript(
+			fmt.Sprintf("[ ! -e %s ] && [ ! -L %s ] || fail 'path' %s 'exists'", path, path, path),
+		))
+	}
+	assertions := []string{
+		fmt.Sprintf(`if [ ! -e %s ] && [ ! -L %s ]; then echo "FAIL: path "%s" does not exist"; exit 1; fi`, path, path, path),
+		fmt.Sprintf(`if [ -L %s ]; then type=symlink; elif [ -d %s ]; then type=directory; elif [ -f %s ]; then type=file; else type=other; fi`,
+			path, path, path),
+		fmt.Sprintf(`owner=$(stat -c %%U %s); group=$(stat -c %%G %s); mode=$(stat -c %%a %s); size=$(stat -c %%s %s)`,
+			path, path, path, path),
+		`echo "type=$type"; echo "owner=$owner"; echo "group=$group"; echo "mode=$mode"; echo "size=$size"`,
+	}
+	if ftc.fileType != "" {
+		assertions = append(assertions, expectEqual("type", "$type", string(ftc.fileType)))
+	}
+	if ftc.owner != "" {
+		assertions = append(assertions, expectEqual("owner", "$owner", ftc.owner))
+	}
+	if ftc.group != "" {
+		assertions = append(assertions, expectEqual("group", "$group", ftc.group))
+	}
+	if ftc.mode != "" {
+		// stat reports the mode without leading zeros
+		assertions = append(assertions, fmt.Sprintf(`[ "$mode" -eq %s ] 2>/dev/null || fail 'mode is' "$mode" 'expected' %s`,
+			shellQuote("0"+ftc.mode), shellQuote(ftc.mode)))
+	}
+	if ftc.minSize >= 0 {
+		assertions = append(assertions, fmt.Sprintf(`[ "$size" -ge %d ] || fail 'size is' "$size" 'expected at least %d'`, ftc.minSize, ftc.minSize))
+	}
+	if ftc.maxSize >= 0 {
+		assertions = append(assertions, fmt.Sprintf(`[ "$size" -le %d ] || fail 'size is' "$size" 'expected at most %d'`, ftc.maxSize, ftc.maxSize))
+	}
+	if ftc.sha256 != "" {
+		assertions = append(assertions,
+			fmt.Sprintf(`sha256=$(sha256sum %s | cut -d ' ' -f 1); echo "sha256=$sha256"`, path),
+			expectEqual("sha256", "$sha256", ftc.sha256))
+	}
+	if ftc.contentPattern != "" {
+		assertions = append(assertions, fmt.Sprintf(`grep -Eq %s %s || fail 'content does not match' %s`,
+			shellQuote(ftc.contentPattern), path, shellQuote(ftc.contentPattern)))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// expectEqual returns an assertion that the shell expression actual equals the literal expected, reporting name on failure.
+func expectEqual(name string, actual string, expected string) string {
+	return fmt.Sprintf(`[ "%s" = %s ] || fail '%s is' "%s" 'expected' %s`, actual, shellQuote(expected), name, actual, shellQuote(expected))
+}
+
+// WithType returns a copy of the FileTestCase that checks the path is of fileType.
+func (ftc FileTestCase) WithType(fileType FileType) FileTestCase {
+	ftc.fileType = fileType
+	return ftc
+}
+
+// WithOwner returns a copy of the FileTestCase that checks the path is owned by the user and group names.
+// Either may be empty to not check it.
+func (ftc FileTestCase) WithOwner(owner string, group string) FileTestCase {
+	ftc.owner = owner
+	ftc.group = group
+	return ftc
+}
+
+// WithMode returns a copy of the FileTestCase that checks the access mode of the path, as an octal string eg. "644" or "0755".
+func (ftc FileTestCase) WithMode(mode string) FileTestCase {
+	ftc.mode = mode
+	return ftc
+}
+
+// WithSizeBetween returns a copy of the FileTestCase that checks the size of the path in bytes is between min and max
+// inclusive. Either may be negative to not check that bound.
+func (ftc FileTestCase) WithSizeBetween(min int64, max int64) FileTestCase {
+	ftc.minSize = min
+	ftc.maxSize = max
+	return ftc
+}
+
+// WithSha256 returns a copy of the FileTestCase that checks the hex encoded sha256 checksum of the content of the file.
+func (ftc FileTestCase) WithSha256(sha256 string) FileTestCase {
+	ftc.sha256 = sha256
+	return ftc
+}
+
+// WithContentMatching returns a copy of the FileTestCase that checks that a line of the content of the file matches
+// pattern, an extended regular expression as understood by grep -E.
+func (ftc FileTestCase) WithContentMatching(pattern string) FileTestCase {
+	ftc.contentPattern = pattern
+	return ftc
+}
+
+// NewFileTestCase is a constructor for FileTestCase type.
+// path should be the absolute path of the file or directory on the target instances. By default the test only checks
+// that the path exists, further checks are added with the With methods.
+func NewFileTestCase(path string) FileTestCase {
+	return FileTestCase{
+		path:    path,
+		minSize: -1,
+		maxSize: -1,
+	}
+}
+
+// NewAbsentFileTestCase returns a FileTestCase that checks that path does not exist on the target instances.
+func NewAbsentFileTestCase(path string) FileTestCase {
+	ftc := NewFileTestCase(path)
+	ftc.absent = true
+	return ftc
+}
@@ -0,0 +1,79 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFileTestCase(t *testing.T) {
+	cases := []struct {
+		caseName                  string
+		fileTestCase              FileTestCase
+		expectedCommandParameters map[string][]string
+	}{
+		{
+			caseName:     "Should only check that the path exists by default",
+			fileTestCase: NewFileTestCase("/etc/app.conf"),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`if [ ! -e '/etc/app.conf' ] && [ ! -L '/etc/app.conf' ]; then echo "FAIL: path "'/etc/app.conf'" does not exist"; exit 1; fi`,
+					`if [ -L '/etc/app.conf' ]; then type=symlink; elif [ -d '/etc/app.conf' ]; then type=directory; elif [ -f '/etc/app.conf' ]; then type=file; else type=other; fi`,
+					`owner=$(stat -c %U '/etc/app.conf'); group=$(stat -c %G '/etc/app.conf'); mode=$(stat -c %a '/etc/app.conf'); size=$(stat -c %s '/etc/app.conf')`,
+					`echo "type=$type"; echo "owner=$owner"; echo "group=$group"; echo "mode=$mode"; echo "size=$size"`,
+					"exit $rc",
+				},
+			},
+		},
+		{
+			caseName: "Should check every configured property",
+			fileTestCase: NewFileTestCase("/etc/app.conf").
+				WithType(RegularFile).
+				WithOwner("root", "app").
+				WithMode("0640").
+				WithSizeBetween(1, 1024).
+				WithSha256("abc123").
+				WithContentMatching("^level=info$"),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`if [ ! -e '/etc/app.conf' ] && [ ! -L '/etc/app.conf' ]; then echo "FAIL: path "'/etc/app.conf'" does not exist"; exit 1; fi`,
+					`if [ -L '/etc/app.conf' ]; then type=symlink; elif [ -d '/etc/app.conf' ]; then type=directory; elif [ -f '/etc/app.conf' ]; then type=file; else type=other; fi`,
+					`owner=$(stat -c %U '/etc/app.conf'); group=$(stat -c %G '/etc/app.conf'); mode=$(stat -c %a '/etc/app.conf'); size=$(stat -c %s '/etc/app.conf')`,
+					`echo "type=$type"; echo "owner=$owner"; echo "group=$group"; echo "mode=$mode"; echo "size=$size"`,
+					`[ "$type" = 'file' ] || fail 'type is' "$type" 'expected' 'file'`,
+					`[ "$owner" = 'root' ] || fail 'owner is' "$owner" 'expected' 'root'`,
+					`[ "$group" = 'app' ] || fail 'group is' "$group" 'expected' 'app'`,
+					`[ "$mode" -eq '00640' ] 2>/dev/null || fail 'mode is' "$mode" 'expected' '0640'`,
+					`[ "$size" -ge 1 ] || fail 'size is' "$size" 'expected at least 1'`,
+					`[ "$size" -le 1024 ] || fail 'size is' "$size" 'expected at most 1024'`,
+					`sha256=$(sha256sum '/etc/app.conf' | cut -d ' ' -f 1); echo "sha256=$sha256"`,
+					`[ "$sha256" = 'abc123' ] || fail 'sha256 is' "$sha256" 'expected' 'abc123'`,
+					`grep -Eq '^level=info$' '/etc/app.conf' || fail 'content does not match' '^level=info$'`,
+					"exit $rc",
+				},
+			},
+		},
+		{
+			caseName:     "Should check that the path does not exist",
+			fileTestCase: NewAbsentFileTestCase("/home/ec2-user/.ssh/authorized_keys"),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`[ ! -e '/home/ec2-user/.ssh/authorized_keys' ] && [ ! -L '/home/ec2-user/.ssh/authorized_keys' ] || fail 'path' '/home/ec2-user/.ssh/authorized_keys' 'exists'`,
+					"exit $rc",
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if e, a := c.expectedCommandParameters, c.fileTestCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package tester
+
+import "fmt"
+
+// FilesystemTestCase configuration for a test that checks a mount point on the target instances: that it is mounted,
+// its filesystem type and mount options eg. noexec on /tmp, and that it has free space and inodes above thresholds, eg. to
+// catch log volumes filling up before instances start falling over.
+// The actual state of the mount point is reported as name=value pairs in the output, available with
+// InvocationResult.Values.
+type FilesystemTestCase struct {
+	runShellScript
+	mountPoint           string   // the path the filesystem should be mounted at
+	fsType               string   // the expected filesystem type eg. xfs, not checked if empty
+	mountOptions         []string // the mount options that should be set eg. noexec
+	minFreePercent       int      // the minimum percentage of free space, not checked if negative
+	minFreeBytes         int64    // the minimum number of free bytes, not checked if negative
+	minFreeInodesPercent int      // the minimum percentage of free inodes, not checked if negative
+}
+
+func (fstc FilesystemTestCase) buildCommandParameters() map[string][]string {
+	mountPoint := shellQuote(fstc.mountPoint)
+	assertions := []string{
+		fmt.Sprintf(`mount=$(awk -v m=%s '$2 == m { print $3, $4 }' /proc/mounts | tail -1)`, mountPoint),
+		fmt.Sprintf(`if [ -z "$mount" ]; then echo "FAIL: nothing is mounted at "%s; exit 1; fi`, mountPoint),
+		`fstype=${mount%% *}; options=${mount#* }`,
+		fmt.Sprintf(`usage=$(df -Pk %s | awk 'NR == 2 { sub(/%%/, "", $5); print $4, 100 - $5 }')`, mountPoint),
+		fmt.Sprintf(`inodes=$(df -Pi %s | awk 'NR == 2 { sub(/%%/, "", $5); print ($5 == "-" ? "" : 100 - $5) }')`, mountPoint),
+		`free_bytes=$((${usage%% *} * 1024)); free_percent=${usage#* }`,
+		`echo "fstype=$fstype"; echo "options=$options"; echo "free_bytes=$free_bytes"; echo "free_percent=$free_percent"; echo "free_inodes_percent=$inodes"`,
+	}
+	if fstc.fsType != "" {
+		assertions = append(assertions, expectEqual("filesystem type", "$fstype", fstc.fsType))
+	}
+	for _, v := range fstc.mountOptions {
+		assertions = append(assertions, fmt.Sprintf(`case ",$options," in *,%s,*) ;; *) fail 'mount option' %s 'is not set, options are' "$options" ;; esac`,
+			shellQuote(v), shellQuote(v)))
+	}
+	if fstc.minFreePercent >= 0 {
+		assertions = append(assertions, fmt.Sprintf(`[ "$free_percent" -ge %d ] || fail 'free space is' "$free_percent%%" 'expected at least %d%%'`,
+			fstc.minFreePercent, fstc.minFreePercent))
+	}
+	if fstc.minFreeBytes >= 0 {
+		assertions = append(assertions, fmt.Sprintf(`[ "$free_bytes" -ge %d ] || fail 'free space is' "$free_bytes" 'bytes expected at least %d'`,
+			fstc.minFreeBytes, fstc.minFreeBytes))
+	}
+	if fstc.minFreeInodesPercent >= 0 {
+		assertions = append(assertions, fmt.Sprintf(`[ "$inodes" -ge %d ] 2>/dev/null || fail 'free inodes are' "$inodes%%" 'expected at least %d%%'`,
+			fstc.minFreeInodesPercent, fstc.minFreeInodesPercent))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// WithType returns a copy of the FilesystemTestCase that checks the filesystem type eg. "xfs", "ext4" or "tmpfs".
+func (fstc FilesystemTestCase) WithType(fsType string) FilesystemTestCase {
+	fstc.fsType = fsType
+	return fstc
+}
+
+// WithMountOptions returns a copy of the FilesystemTestCase that checks each of options is set on the mount eg. "noexec".
+func (fstc FilesystemTestCase) WithMountOptions(options ...string) FilesystemTestCase {
+	fstc.mountOptions = options
+	return fstc
+}
+
+// WithMinFreePercent returns a copy of the FilesystemTestCase that checks at least percent of the space of the
+// filesystem is available.
+func (fstc FilesystemTestCase) WithMinFreePercent(percent int) FilesystemTestCase {
+	fstc.minFreePercent = percent
+	return fstc
+}
+
+// WithMinFreeBytes returns a copy of the FilesystemTestCase that checks at least bytes are available on the filesystem.
+func (fstc FilesystemTestCase) WithMinFreeBytes(bytes int64) FilesystemTestCase {
+	fstc.minFreeBytes = bytes
+	return fstc
+}
+
+// WithMinFreeInodesPercent returns a copy of the FilesystemTestCase that checks at least percent of the inodes of the
+// filesystem are free. The check fails for filesystems that do not report inode usage.
+func (fstc FilesystemTestCase) WithMinFreeInodesPercent(percent int) FilesystemTestCase {
+	fstc.minFreeInodesPercent = percent
+	return fstc
+}
+
+// NewFilesystemTestCase is a constructor for FilesystemTestCase type.
+// mountPoint should be the absolute path a filesystem is mounted at on the target instances eg. "/var/log".
+// By default the test only checks that a filesystem is mounted at mountPoint, further checks are added with the
+// With methods.
+func NewFilesystemTestCase(mountPoint string) FilesystemTestCase {
+	return FilesystemTestCase{
+		mountPoint:           mountPoint,
+		minFreePercent:       -1,
+		minFreeBytes:         -1,
+		minFreeInodesPercent: -1,
+	}
+}
@@ -0,0 +1,38 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilesystemTestCase(t *testing.T) {
+	testCase := NewFilesystemTestCase("/tmp").
+		WithType("tmpfs").
+		WithMountOptions("noexec", "nosuid").
+		WithMinFreePercent(10).
+		WithMinFreeBytes(1073741824).
+		WithMinFreeInodesPercent(5)
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`mount=$(awk -v m='/tmp' '$2 == m { print $3, $4 }' /proc/mounts | tail -1)`,
+			`if [ -z "$mount" ]; then echo "FAIL: nothing is mounted at "'/tmp'; exit 1; fi`,
+			`fstype=${mount%% *}; options=${mount#* }`,
+			`usage=$(df -Pk '/tmp' | awk 'NR == 2 { sub(/%/, "", $5); print $4, 100 - $5 }')`,
+			`inodes=$(df -Pi '/tmp' | awk 'NR == 2 { sub(/%/, "", $5); print ($5 == "-" ? "" : 100 - $5) }')`,
+			`free_bytes=$((${usage%% *} * 1024)); free_percent=${usage#* }`,
+			`echo "fstype=$fstype"; echo "options=$options"; echo "free_bytes=$free_bytes"; echo "free_percent=$free_percent"; echo "free_inodes_percent=$inodes"`,
+			`[ "$fstype" = 'tmpfs' ] || fail 'filesystem type is' "$fstype" 'expected' 'tmpfs'`,
+			`case ",$options," in *,'noexec',*) ;; *) fail 'mount option' 'noexec' 'is not set, options are' "$options" ;; esac`,
+			`case ",$options," in *,'nosuid',*) ;; *) fail 'mount option' 'nosuid' 'is not set, options are' "$options" ;; esac`,
+			`[ "$free_percent" -ge 10 ] || fail 'free space is' "$free_percent%" 'expected at least 10%'`,
+			`[ "$free_bytes" -ge 1073741824 ] || fail 'free space is' "$free_bytes" 'bytes expected at least 1073741824'`,
+			`[ "$inodes" -ge 5 ] 2>/dev/null || fail 'free inodes are' "$inodes%" 'expected at least 5%'`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
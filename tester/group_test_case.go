@@ -0,0 +1,71 @@
+package tester
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// GroupTestCase configuration for a test that checks a local group on the target instances exists, or is absent, and
+// its gid and members.
+// The actual state of the group is reported as name=value pairs in the output, available with InvocationResult.Values.
+type GroupTestCase struct {
+	runShellScript
+	name    string   // the name of the group
+	absent  bool     // check that the group does not exist
+	gid     int      // the expected gid, not checked if negative
+	members []string // the users that should be members of the group
+}
+
+func (gtc GroupTestCase) buildCommandParameters() map[string][]string {
+	name := shellQuote(gtc.name)
+	if gtc.absent {
+		return shellCommandParameters(assertionScript(
+			fmt.Sprintf(`[ -z "$(getent group %s)" ] || fail 'group' %s 'exists'`, name, name),
+		))
+	}
+	assertions := []string{
+		fmt.Sprintf(`entry=$(getent group %s)`, name),
+		fmt.Sprintf(`if [ -z "$entry" ]; then echo "FAIL: group "%s" does not exist"; exit 1; fi`, name),
+		`gid=$(echo "$entry" | cut -d : -f 3); members=$(echo "$entry" | cut -d : -f 4)`,
+		`echo "gid=$gid"; echo "members=$members"`,
+	}
+	if gtc.gid >= 0 {
+		assertions = append(assertions, expectEqual("gid", "$gid", strconv.Itoa(gtc.gid)))
+	}
+	for _, v := range gtc.members {
+		// users that have the group as their primary group are not listed as members
+		assertions = append(assertions, fmt.Sprintf(`case ",$members," in *,%s,*) ;; *) [ "$(id -gn %s 2>/dev/null)" = %s ] || fail 'user' %s 'is not a member - members are' "$members" ;; esac`,
+			shellQuote(v), shellQuote(v), name, shellQuote(v)))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// WithGid returns a copy of the GroupTestCase that checks the gid of the group.
+func (gtc GroupTestCase) WithGid(gid int) GroupTestCase {
+	gtc.gid = gid
+	return gtc
+}
+
+// WithMembers returns a copy of the GroupTestCase that checks each of members is a member of the group, either as a
+// supplementary member or by having the group as its primary group.
+func (gtc GroupTestCase) WithMembers(members ...string) GroupTestCase {
+	gtc.members = members
+	return gtc
+}
+
+// NewGroupTestCase is a constructor for GroupTestCase type.
+// name should be the name of the group on the target instances. By default the test only checks that the group exists,
+// further checks are added with the With methods.
+func NewGroupTestCase(name string) GroupTestCase {
+	return GroupTestCase{
+		name: name,
+		gid:  -1,
+	}
+}
+
+// NewAbsentGroupTestCase returns a GroupTestCase that checks that the group name does not exist on the target instances.
+func NewAbsentGroupTestCase(name string) GroupTestCase {
+	gtc := NewGroupTestCase(name)
+	gtc.absent = true
+	return gtc
+}
@@ -0,0 +1,42 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupTestCase(t *testing.T) {
+	testCase := NewGroupTestCase("docker").
+		WithGid(992).
+		WithMembers("ec2-user")
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`entry=$(getent group 'docker')`,
+			`if [ -z "$entry" ]; then echo "FAIL: group "'docker'" does not exist"; exit 1; fi`,
+			`gid=$(echo "$entry" | cut -d : -f 3); members=$(echo "$entry" | cut -d : -f 4)`,
+			`echo "gid=$gid"; echo "members=$members"`,
+			`[ "$gid" = '992' ] || fail 'gid is' "$gid" 'expected' '992'`,
+			`case ",$members," in *,'ec2-user',*) ;; *) [ "$(id -gn 'ec2-user' 2>/dev/null)" = 'docker' ] || fail 'user' 'ec2-user' 'is not a member - members are' "$members" ;; esac`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestAbsentGroupTestCase(t *testing.T) {
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`[ -z "$(getent group 'games')" ] || fail 'group' 'games' 'exists'`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, NewAbsentGroupTestCase("games").buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
@@ -0,0 +1,67 @@
+package tester
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// HttpTestCase configuration for a test that makes an HTTP request from the target instances, and checks the status code
+// and optionally the body of the response, eg. that the health endpoint of an application behind an internal load
+// balancer responds with 200.
+// The status code of the response is reported as a name=value pair in the output, available with
+// InvocationResult.Values.
+//
+// The test relies on the curl binary being installed on the target instances.
+type HttpTestCase struct {
+	runShellScript
+	url              string // the url to request
+	expectedStatus   int    // the expected status code of the response
+	bodyPattern      string // an extended regular expression the body should match, not checked if empty
+	timeoutInSeconds int    // the time to wait for the response
+}
+
+func (htc HttpTestCase) buildCommandParameters() map[string][]string {
+	url := shellQuote(htc.url)
+	assertions := []string{
+		// the status code is written on a line of its own after the body
+		fmt.Sprintf(`response=$(curl -s --max-time %d -w '\n%%{http_code}' %s)`, htc.timeoutInSeconds, url),
+		`status=$(echo "$response" | tail -n 1); body=$(echo "$response" | sed '$d')`,
+		`echo "status=$status"`,
+		fmt.Sprintf(`if [ "$status" = 000 ]; then echo "FAIL: no response from "%s; exit 1; fi`, url),
+		expectEqual("status", "$status", strconv.Itoa(htc.expectedStatus)),
+	}
+	if htc.bodyPattern != "" {
+		assertions = append(assertions, fmt.Sprintf(`echo "$body" | grep -Eq %s || fail 'body does not match' %s`,
+			shellQuote(htc.bodyPattern), shellQuote(htc.bodyPattern)))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// WithStatus returns a copy of the HttpTestCase that checks the status code of the response is status.
+func (htc HttpTestCase) WithStatus(status int) HttpTestCase {
+	htc.expectedStatus = status
+	return htc
+}
+
+// WithBodyMatching returns a copy of the HttpTestCase that checks the body of the response matches the extended
+// regular expression pattern eg. `"status": *"UP"`.
+func (htc HttpTestCase) WithBodyMatching(pattern string) HttpTestCase {
+	htc.bodyPattern = pattern
+	return htc
+}
+
+// WithTimeout returns a copy of the HttpTestCase that waits timeoutInSeconds for the response.
+func (htc HttpTestCase) WithTimeout(timeoutInSeconds int) HttpTestCase {
+	htc.timeoutInSeconds = timeoutInSeconds
+	return htc
+}
+
+// NewHttpTestCase is a constructor for HttpTestCase type.
+// url is the url to request eg. "http://localhost:8080/health". By default the test checks the status code is 200.
+func NewHttpTestCase(url string) HttpTestCase {
+	return HttpTestCase{
+		url:              url,
+		expectedStatus:   200,
+		timeoutInSeconds: 5,
+	}
+}
@@ -0,0 +1,29 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHttpTestCase(t *testing.T) {
+	testCase := NewHttpTestCase("http://localhost:8080/health").
+		WithStatus(204).
+		WithBodyMatching(`"status": *"UP"`).
+		WithTimeout(2)
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`response=$(curl -s --max-time 2 -w '\n%{http_code}' 'http://localhost:8080/health')`,
+			`status=$(echo "$response" | tail -n 1); body=$(echo "$response" | sed '$d')`,
+			`echo "status=$status"`,
+			`if [ "$status" = 000 ]; then echo "FAIL: no response from "'http://localhost:8080/health'; exit 1; fi`,
+			`[ "$status" = '204' ] || fail 'status is' "$status" 'expected' '204'`,
+			`echo "$body" | grep -Eq '"status": *"UP"' || fail 'body does not match' '"status": *"UP"'`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
@@ -0,0 +1,132 @@
+package tester
+
+import "fmt"
+
+// IamVerdict is the verdict of an IamPermissionTestCase on whether the instance profile of an instance allows an action.
+type IamVerdict string
+
+const (
+	// IamAllowed means the call made on the instance succeeded.
+	IamAllowed IamVerdict = "allowed"
+	// IamDenied means the call made on the instance was rejected with an access denied error.
+	IamDenied IamVerdict = "denied"
+	// IamUnknown means the call made on the instance failed for another reason, eg. no network path to the AWS API
+	// endpoint or a resource that does not exist, so the test cannot tell if the action is allowed or not.
+	IamUnknown IamVerdict = "unknown"
+)
+
+// IamPermissionTestCase configuration for a test that runs an AWS CLI command on the target instances, to confirm that the
+// instance profile allows or denies an IAM action.
+// The test passes on an instance if the verdict for the action matches the expected verdict. The verdict for each
+// instance is available from the TestResult with Verdicts.
+//
+// Important Note - the test relies on the aws cli being installed on the target instances.
+type IamPermissionTestCase struct {
+	runShellScript
+	action   string     // the IAM action exercised by the command, eg. s3:GetObject
+	command  string     // the aws cli command that exercises the action
+	region   string     // the region to make the call in, the default region of the aws cli if empty
+	expected IamVerdict // the verdict the test expects
+}
+
+// access denied errors as reported by the aws cli for the different AWS API protocols
+const iamAccessDeniedPattern = `AccessDenied|UnauthorizedOperation|not authorized to perform|Forbidden|\(403\)`
+
+func (iptc IamPermissionTestCase) buildCommandParameters() map[string][]string {
+	command := iptc.command
+	if iptc.region != "" {
+		command = fmt.Sprintf("%s --region %s", command, shellQuote(iptc.region))
+	}
+	return shellCommandParameters(assertionScript(
+		fmt.Sprintf("out=$(%s 2>&1)", command),
+		"status=$?",
+		fmt.Sprintf(`if [ $status -eq 0 ]; then verdict=%s; elif echo "$out" | grep -Eq %s; then verdict=%s; else verdict=%s; fi`,
+			IamAllowed, shellQuote(iamAccessDeniedPattern), IamDenied, IamUnknown),
+		`echo "verdict=$verdict"`,
+		fmt.Sprintf(`[ "$verdict" = %s ] || fail %s "$verdict" '-' "$out"`,
+			iptc.expected, shellQuote(fmt.Sprintf("%s expected %s, got", iptc.action, iptc.expected))),
+	))
+}
+
+// WithRegion returns a copy of the IamPermissionTestCase that makes the call in region.
+func (iptc IamPermissionTestCase) WithRegion(region string) IamPermissionTestCase {
+	iptc.region = region
+	return iptc
+}
+
+// Verdicts returns the verdict for each instance in result, keyed by instance id.
+// result should be the TestResult of running the IamPermissionTestCase, eg. with RunTestCaseForTargetWithResultsE.
+func (iptc IamPermissionTestCase) Verdicts(result TestResult) map[string]IamVerdict {
+	verdicts := map[string]IamVerdict{}
+	for _, v := range result.Invocations {
+		verdict := IamVerdict(v.Values()["verdict"])
+		if verdict == "" {
+			verdict = IamUnknown
+		}
+		verdicts[v.InstanceId] = verdict
+	}
+	return verdicts
+}
+
+// NewIamPermissionTestCase is a constructor for IamPermissionTestCase type.
+// action is the IAM action exercised by the command and is used to report on the test eg. "dynamodb:GetItem".
+// command is the aws cli command to run on the instance eg. "aws dynamodb get-item --table-name app --key '{...}'".
+// expected is the verdict the test should check for, either IamAllowed or IamDenied.
+func NewIamPermissionTestCase(action string, command string, expected IamVerdict) IamPermissionTestCase {
+	return IamPermissionTestCase{
+		action:   action,
+		command:  command,
+		expected: expected,
+	}
+}
+
+// NewS3GetObjectTestCase returns an IamPermissionTestCase for the s3:GetObject action on bucket and key.
+// The object is downloaded to /dev/null.
+func NewS3GetObjectTestCase(bucket string, key string, expected IamVerdict) IamPermissionTestCase {
+	command := fmt.Sprintf("aws s3api get-object --bucket %s --key %s /dev/null", shellQuote(bucket), shellQuote(key))
+	return NewIamPermissionTestCase("s3:GetObject", command, expected)
+}
+
+// NewSecretsManagerGetSecretValueTestCase returns an IamPermissionTestCase for the secretsmanager:GetSecretValue
+// action on secretId, which may be the name or ARN of the secret.
+// Only the ARN of the secret is queried for, so that the value of the secret does not end up in the command output.
+func NewSecretsManagerGetSecretValueTestCase(secretId string, expected IamVerdict) IamPermissionTestCase {
+	command := fmt.Sprintf("aws secretsmanager get-secret-value --secret-id %s --query ARN --output text", shellQuote(secretId))
+	return NewIamPermissionTestCase("secretsmanager:GetSecretValue", command, expected)
+}
+
+// CallerIdentityTestCase configuration for a test that checks that the AWS credentials available on the target instances
+// are for the expected IAM role, ie. that the instance profile is attached and is for the right role.
+// The ARN of the caller identity for each instance is available from the TestResult with Arns.
+//
+// Important Note - the test relies on the aws cli being installed on the target instances.
+type CallerIdentityTestCase struct {
+	runShellScript
+	roleName string // the name of the role the instance credentials should be for
+}
+
+func (citc CallerIdentityTestCase) buildCommandParameters() map[string][]string {
+	return shellCommandParameters(assertionScript(
+		"arn=$(aws sts get-caller-identity --query Arn --output text 2>&1)",
+		`echo "arn=$arn"`,
+		fmt.Sprintf(`case "$arn" in *:assumed-role/%s/*) ;; *) fail 'caller identity is not for role' %s '-' "$arn" ;; esac`,
+			shellQuote(citc.roleName), shellQuote(citc.roleName)),
+	))
+}
+
+// Arns returns the ARN of the caller identity on each instance in result, keyed by instance id.
+func (citc CallerIdentityTestCase) Arns(result TestResult) map[string]string {
+	arns := map[string]string{}
+	for _, v := range result.Invocations {
+		arns[v.InstanceId] = v.Values()["arn"]
+	}
+	return arns
+}
+
+// NewCallerIdentityTestCase is a constructor for CallerIdentityTestCase type.
+// roleName is the name of the IAM role the instance profile of the target instances should be for.
+func NewCallerIdentityTestCase(roleName string) CallerIdentityTestCase {
+	return CallerIdentityTestCase{
+		roleName: roleName,
+	}
+}
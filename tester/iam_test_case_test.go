@@ -0,0 +1,66 @@
+package tester
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"reflect"
+	"testing"
+)
+
+func TestIamPermissionTestCase(t *testing.T) {
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`out=$(aws secretsmanager get-secret-value --secret-id 'app/db' --query ARN --output text --region 'ap-southeast-2' 2>&1)`,
+			"status=$?",
+			`if [ $status -eq 0 ]; then verdict=allowed; elif echo "$out" | grep -Eq 'AccessDenied|UnauthorizedOperation|not authorized to perform|Forbidden|\(403\)'; then verdict=denied; else verdict=unknown; fi`,
+			`echo "verdict=$verdict"`,
+			`[ "$verdict" = allowed ] || fail 'secretsmanager:GetSecretValue expected allowed, got' "$verdict" '-' "$out"`,
+			"exit $rc",
+		},
+	}
+	testCase := NewSecretsManagerGetSecretValueTestCase("app/db", IamAllowed).WithRegion("ap-southeast-2")
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestIamPermissionTestCaseVerdicts(t *testing.T) {
+	result := TestResult{
+		Invocations: []InvocationResult{
+			{InstanceId: "i-1", Status: types.CommandInvocationStatusSuccess, Output: "verdict=allowed\n"},
+			{InstanceId: "i-2", Status: types.CommandInvocationStatusFailed, Output: "verdict=denied\nFAIL: s3:GetObject expected allowed, got denied\n"},
+			{InstanceId: "i-3", Status: types.CommandInvocationStatusTimedOut},
+		},
+	}
+	expected := map[string]IamVerdict{"i-1": IamAllowed, "i-2": IamDenied, "i-3": IamUnknown}
+	if a := NewS3GetObjectTestCase("bucket", "key", IamAllowed).Verdicts(result); !reflect.DeepEqual(expected, a) {
+		t.Errorf("Expected %v, but got %v", expected, a)
+	}
+}
+
+func TestCallerIdentityTestCase(t *testing.T) {
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			"arn=$(aws sts get-caller-identity --query Arn --output text 2>&1)",
+			`echo "arn=$arn"`,
+			`case "$arn" in *:assumed-role/'app-role'/*) ;; *) fail 'caller identity is not for role' 'app-role' '-' "$arn" ;; esac`,
+			"exit $rc",
+		},
+	}
+	testCase := NewCallerIdentityTestCase("app-role")
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+	result := TestResult{
+		Invocations: []InvocationResult{
+			{InstanceId: "i-1", Output: "arn=arn:aws:sts::123456789012:assumed-role/app-role/i-1\n"},
+		},
+	}
+	expected := map[string]string{"i-1": "arn:aws:sts::123456789012:assumed-role/app-role/i-1"}
+	if a := testCase.Arns(result); !reflect.DeepEqual(expected, a) {
+		t.Errorf("Expected %v, but got %v", expected, a)
+	}
+}
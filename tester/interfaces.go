@@ -2,6 +2,7 @@ package tester
 
 import (
 	"context"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
 )
@@ -20,6 +21,10 @@ type commandSenderLister interface {
 	commandLister
 }
 
+type instanceDescriber interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+}
+
 // Interface type to abstract testCases from the tester
 type commandParameterBuilder interface {
 	documentName() string
@@ -0,0 +1,124 @@
+package tester
+
+import (
+	"encoding/json"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"io"
+	"time"
+)
+
+// ReportMetadata describes a run of a suite, for the reports written by WriteJsonReport.
+type ReportMetadata struct {
+	Name    string    // the name of the run eg. the name of the suite file
+	Account string    // the AWS account the suite ran against
+	Region  string    // the AWS region the suite ran against
+	Time    time.Time // the time the run started
+	GitSha  string    // the git commit of the infrastructure or suite under test, omitted if empty
+}
+
+type jsonReport struct {
+	Name    string           `json:"name"`
+	Account string           `json:"account"`
+	Region  string           `json:"region"`
+	Time    time.Time        `json:"time"`
+	GitSha  string           `json:"gitSha,omitempty"`
+	Passed  bool             `json:"passed"`
+	Tests   []jsonReportTest `json:"tests"`
+}
+
+type jsonReportTest struct {
+	Name            string               `json:"name"`
+	Target          string               `json:"target"`
+	Targets         []jsonReportTarget   `json:"targets"`
+	DocumentName    string               `json:"documentName"`
+	Parameters      map[string][]string  `json:"parameters"`
+	CommandId       string               `json:"commandId"`
+	Passed          bool                 `json:"passed"`
+	Error           string               `json:"error,omitempty"`
+	StartedAt       time.Time            `json:"startedAt"`
+	DurationSeconds float64              `json:"durationSeconds"`
+	Instances       []jsonReportInstance `json:"instances"`
+}
+
+type jsonReportTarget struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+type jsonReportInstance struct {
+	InstanceId        string                        `json:"instanceId"`
+	Status            types.CommandInvocationStatus `json:"status"`
+	StatusDetails     string                        `json:"statusDetails,omitempty"`
+	ResponseCode      int32                         `json:"responseCode"`
+	RequestedDateTime *time.Time                    `json:"requestedDateTime,omitempty"`
+	Output            string                        `json:"output"`
+	Values            map[string]string             `json:"values,omitempty"`
+	Failures          []string                      `json:"failures,omitempty"`
+}
+
+// WriteJsonReport writes the result as a JSON document to w, so that it can be archived as evidence of what was verified,
+// or loaded into dashboards.
+//
+// The document has the metadata of the run, and for each test the SSM document, parameters and targets of the test
+// command, the command id, and the outcome on each target instance, with the name=value pairs and failures reported by
+// the command.
+func WriteJsonReport(w io.Writer, metadata ReportMetadata, result SuiteResult) error {
+	report := jsonReport{
+		Name:    metadata.Name,
+		Account: metadata.Account,
+		Region:  metadata.Region,
+		Time:    metadata.Time,
+		GitSha:  metadata.GitSha,
+		Passed:  result.Passed(),
+		Tests:   []jsonReportTest{},
+	}
+	for _, test := range result.Tests {
+		reportTest := jsonReportTest{
+			Name:            test.Name,
+			Target:          test.Target,
+			Targets:         []jsonReportTarget{},
+			DocumentName:    test.DocumentName,
+			Parameters:      test.Parameters,
+			CommandId:       test.Result.CommandId,
+			Passed:          test.Passed,
+			StartedAt:       test.StartedAt,
+			DurationSeconds: test.Duration.Seconds(),
+			Instances:       []jsonReportInstance{},
+		}
+		if test.Err != nil {
+			reportTest.Error = test.Err.Error()
+		}
+		for _, v := range test.Targets {
+			target := jsonReportTarget{Values: v.Values}
+			if v.Key != nil {
+				target.Key = *v.Key
+			}
+			reportTest.Targets = append(reportTest.Targets, target)
+		}
+		for _, v := range test.Result.Invocations {
+			instance := jsonReportInstance{
+				InstanceId:    v.InstanceId,
+				Status:        v.Status,
+				StatusDetails: v.StatusDetails,
+				ResponseCode:  v.ResponseCode,
+				Output:        v.Output,
+				Failures:      v.Failures(),
+			}
+			if !v.RequestedDateTime.IsZero() {
+				requested := v.RequestedDateTime
+				instance.RequestedDateTime = &requested
+			}
+			if values := v.Values(); len(values) > 0 {
+				instance.Values = values
+			}
+			reportTest.Instances = append(reportTest.Instances, instance)
+		}
+		report.Tests = append(report.Tests, reportTest)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	// the parameters are shell scripts, which are easier to read without < and > escaped
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(report)
+}
@@ -0,0 +1,92 @@
+package tester
+
+import (
+	"bytes"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"testing"
+	"time"
+)
+
+func TestWriteJsonReport(t *testing.T) {
+	started := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	metadata := ReportMetadata{Name: "suite.yaml", Account: "123456789012", Region: "ap-southeast-2", Time: started, GitSha: "abc123"}
+	result := SuiteResult{Tests: []SuiteTestResult{{
+		Name:         "app can connect to the database",
+		Target:       "app",
+		Passed:       false,
+		Err:          errors.New("command invocations failed for instanceId i-2"),
+		Duration:     1500 * time.Millisecond,
+		StartedAt:    started,
+		DocumentName: "AWS-RunShellScript",
+		Parameters:   map[string][]string{"commands": {"timeout 3 bash -c '</dev/tcp/db/5432'"}},
+		Targets:      []types.Target{{Key: stringPointer("tag:Name"), Values: []string{"app"}}},
+		Result: TestResult{CommandId: "command-1", Invocations: []InvocationResult{
+			{InstanceId: "i-1", Status: types.CommandInvocationStatusSuccess, Output: "port=5432\n", RequestedDateTime: started},
+			{InstanceId: "i-2", Status: types.CommandInvocationStatusFailed, Output: "FAIL: cannot connect\n", StatusDetails: "Failed", ResponseCode: 1},
+		}},
+	}}}
+	expected := `{
+  "name": "suite.yaml",
+  "account": "123456789012",
+  "region": "ap-southeast-2",
+  "time": "2021-07-01T10:00:00Z",
+  "gitSha": "abc123",
+  "passed": false,
+  "tests": [
+    {
+      "name": "app can connect to the database",
+      "target": "app",
+      "targets": [
+        {
+          "key": "tag:Name",
+          "values": [
+            "app"
+          ]
+        }
+      ],
+      "documentName": "AWS-RunShellScript",
+      "parameters": {
+        "commands": [
+          "timeout 3 bash -c '</dev/tcp/db/5432'"
+        ]
+      },
+      "commandId": "command-1",
+      "passed": false,
+      "error": "command invocations failed for instanceId i-2",
+      "startedAt": "2021-07-01T10:00:00Z",
+      "durationSeconds": 1.5,
+      "instances": [
+        {
+          "instanceId": "i-1",
+          "status": "Success",
+          "responseCode": 0,
+          "requestedDateTime": "2021-07-01T10:00:00Z",
+          "output": "port=5432\n",
+          "values": {
+            "port": "5432"
+          }
+        },
+        {
+          "instanceId": "i-2",
+          "status": "Failed",
+          "statusDetails": "Failed",
+          "responseCode": 1,
+          "output": "FAIL: cannot connect\n",
+          "failures": [
+            "cannot connect"
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+	var buffer bytes.Buffer
+	if err := WriteJsonReport(&buffer, metadata, result); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if e, a := expected, buffer.String(); e != a {
+		t.Errorf("Expected the report to be \n%s, but got \n%s", e, a)
+	}
+}
@@ -0,0 +1,142 @@
+package tester
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"io"
+	"strings"
+)
+
+// ssmErrorSeparator separates the standard output from the standard error in the output of a command plugin.
+const ssmErrorSeparator = "----------ERROR-------"
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	ClassName  string          `xml:"classname,attr"`
+	Name       string          `xml:"name,attr"`
+	Time       string          `xml:"time,attr,omitempty"` // not set for instances, which are not timed separately
+	Properties []junitProperty `xml:"properties>property"`
+	Failure    *junitMessage   `xml:"failure"`
+	Error      *junitMessage   `xml:"error"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+	SystemErr  string          `xml:"system-err,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnitReport writes the result as JUnit XML to w, so that it can be rendered by CI systems, eg. CircleCI or
+// Jenkins. name is the name of the report, eg. the name of the suite file.
+//
+// Each test is written as a testsuite, with the SSM command id and target as properties, and a testcase for each target
+// instance named after the instance id. The testcase fails if the command did not succeed on the instance, and has the
+// standard output and standard error of the command on the instance. A test that could not be run on any instance, eg.
+// because no instances matched its target, is written as a single testcase with an error.
+//
+// The duration of the test is the time of the testsuite. The testcases of the instances have no time, as the command
+// runs on them concurrently and is not timed per instance.
+func WriteJUnitReport(w io.Writer, name string, result SuiteResult) error {
+	report := junitTestSuites{Name: name}
+	for _, test := range result.Tests {
+		suite := junitTestSuite{
+			Name: test.Name,
+			Time: fmt.Sprintf("%.3f", test.Duration.Seconds()),
+			Properties: []junitProperty{
+				{Name: "commandId", Value: test.Result.CommandId},
+				{Name: "target", Value: test.Target},
+			},
+		}
+		for _, invocation := range test.Result.Invocations {
+			stdout, stderr := splitInvocationOutput(invocation.Output)
+			testCase := junitTestCase{
+				ClassName: test.Name,
+				Name:      invocation.InstanceId,
+				Properties: []junitProperty{
+					{Name: "commandId", Value: test.Result.CommandId},
+					{Name: "status", Value: string(invocation.Status)},
+				},
+				SystemOut: stdout,
+				SystemErr: stderr,
+			}
+			if invocation.Status != types.CommandInvocationStatusSuccess {
+				message := fmt.Sprintf("command %s on instance %s", invocation.Status, invocation.InstanceId)
+				if failures := invocation.Failures(); len(failures) > 0 {
+					message = strings.Join(failures, "; ")
+				}
+				testCase.Failure = &junitMessage{Message: message, Type: string(invocation.Status), Text: invocation.Output}
+				suite.Failures++
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+		if len(suite.TestCases) == 0 {
+			message := "test command was not run on any instance"
+			if test.Err != nil {
+				message = test.Err.Error()
+			}
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				ClassName:  test.Name,
+				Name:       test.Name,
+				Time:       suite.Time,
+				Properties: []junitProperty{{Name: "commandId", Value: test.Result.CommandId}},
+				Error:      &junitMessage{Message: message},
+			})
+			suite.Errors++
+		} else if !test.Passed && suite.Failures == 0 {
+			// every invocation succeeded, but the test did not pass, so the reason is reported on the first of them
+			suite.TestCases[0].Error = &junitMessage{Message: fmt.Sprint(test.Err)}
+			suite.Errors++
+		}
+		suite.Tests = len(suite.TestCases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// splitInvocationOutput splits the output of a command invocation into its standard output and standard error.
+func splitInvocationOutput(output string) (string, string) {
+	index := strings.Index(output, ssmErrorSeparator)
+	if index < 0 {
+		return output, ""
+	}
+	return strings.TrimRight(output[:index], "\n"), strings.TrimLeft(output[index+len(ssmErrorSeparator):], "\n")
+}
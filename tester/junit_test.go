@@ -0,0 +1,78 @@
+package tester
+
+import (
+	"bytes"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnitReport(t *testing.T) {
+	result := SuiteResult{Tests: []SuiteTestResult{
+		{
+			Name:     "app can connect to the database",
+			Target:   "app",
+			Passed:   false,
+			Err:      errors.New("command invocations failed for instanceId i-2"),
+			Duration: 1500 * time.Millisecond,
+			Result: TestResult{CommandId: "command-1", Invocations: []InvocationResult{
+				{InstanceId: "i-1", Status: types.CommandInvocationStatusSuccess, Output: "connected"},
+				{InstanceId: "i-2", Status: types.CommandInvocationStatusFailed, Output: "FAIL: cannot connect\n----------ERROR-------\nbash: connect: Connection refused"},
+			}},
+		},
+		{
+			Name:     "workers run the agent",
+			Target:   "workers",
+			Passed:   false,
+			Err:      noInvocationFoundError{},
+			Duration: 2 * time.Second,
+			Result:   TestResult{CommandId: "command-2"},
+		},
+	}}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="suite.yaml" tests="3" failures="1" errors="1">
+  <testsuite name="app can connect to the database" tests="2" failures="1" errors="0" time="1.500">
+    <properties>
+      <property name="commandId" value="command-1"></property>
+      <property name="target" value="app"></property>
+    </properties>
+    <testcase classname="app can connect to the database" name="i-1">
+      <properties>
+        <property name="commandId" value="command-1"></property>
+        <property name="status" value="Success"></property>
+      </properties>
+      <system-out>connected</system-out>
+    </testcase>
+    <testcase classname="app can connect to the database" name="i-2">
+      <properties>
+        <property name="commandId" value="command-1"></property>
+        <property name="status" value="Failed"></property>
+      </properties>
+      <failure message="cannot connect" type="Failed">FAIL: cannot connect&#xA;----------ERROR-------&#xA;bash: connect: Connection refused</failure>
+      <system-out>FAIL: cannot connect</system-out>
+      <system-err>bash: connect: Connection refused</system-err>
+    </testcase>
+  </testsuite>
+  <testsuite name="workers run the agent" tests="1" failures="0" errors="1" time="2.000">
+    <properties>
+      <property name="commandId" value="command-2"></property>
+      <property name="target" value="workers"></property>
+    </properties>
+    <testcase classname="workers run the agent" name="workers run the agent" time="2.000">
+      <properties>
+        <property name="commandId" value="command-2"></property>
+      </properties>
+      <error message="no invocations found"></error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	var buffer bytes.Buffer
+	if err := WriteJUnitReport(&buffer, "suite.yaml", result); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if e, a := expected, buffer.String(); e != a {
+		t.Errorf("Expected the report to be \n%s, but got \n%s", e, a)
+	}
+}
@@ -0,0 +1,117 @@
+package tester
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KernelParameterTestCase configuration for a test that checks kernel parameters on the target instances against expected
+// values or ranges, eg. net.ipv4.ip_forward=0 or vm.max_map_count of at least 262144 for Elasticsearch nodes.
+// All the parameters are checked with a single command, and the test reports every mismatch on an instance, not just
+// the first. The actual value of each parameter on each instance is available from the TestResult with Parameters.
+type KernelParameterTestCase struct {
+	runShellScript
+	parameters []kernelParameter // the parameters to check, in the order they were added
+}
+
+type kernelParameter struct {
+	key      string // a sysctl key eg. vm.max_map_count, or an absolute path under /proc or /sys
+	expected string // the expected value with whitespace normalised to single spaces, not checked if empty
+	min      *int64 // the minimum value, not checked if nil
+	max      *int64 // the maximum value, not checked if nil
+}
+
+func (kp kernelParameter) readCommand() string {
+	if strings.HasPrefix(kp.key, "/") {
+		return fmt.Sprintf("cat %s", shellQuote(kp.key))
+	}
+	return fmt.Sprintf("sysctl -n %s", shellQuote(kp.key))
+}
+
+func (kptc KernelParameterTestCase) buildCommandParameters() map[string][]string {
+	var assertions []string
+	for _, v := range kptc.parameters {
+		key := shellQuote(v.key)
+		assertions = append(assertions,
+			fmt.Sprintf(`value=$(%s 2>/dev/null | tr -s ' \t' '  '); echo %s"=$value"`, v.readCommand(), key))
+		var conditions []string
+		var expectations []string
+		if v.expected != "" {
+			conditions = append(conditions, fmt.Sprintf(`[ "$value" = %s ]`, shellQuote(v.expected)))
+			expectations = append(expectations, v.expected)
+		}
+		if v.min != nil {
+			conditions = append(conditions, fmt.Sprintf(`[ "$value" -ge %d ] 2>/dev/null`, *v.min))
+			expectations = append(expectations, fmt.Sprintf("at least %d", *v.min))
+		}
+		if v.max != nil {
+			conditions = append(conditions, fmt.Sprintf(`[ "$value" -le %d ] 2>/dev/null`, *v.max))
+			expectations = append(expectations, fmt.Sprintf("at most %d", *v.max))
+		}
+		check := fmt.Sprintf(`if [ -z "$value" ]; then fail %s 'not found'`, key)
+		if len(conditions) > 0 {
+			check += fmt.Sprintf(`; elif ! { %s; }; then fail %s 'is' "$value" 'expected' %s`,
+				strings.Join(conditions, " && "), key, shellQuote(strings.Join(expectations, " and ")))
+		}
+		assertions = append(assertions, check+"; fi")
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// WithValue returns a copy of the KernelParameterTestCase that checks the value of key is expected.
+// key should be a sysctl key eg. "net.ipv4.ip_forward", or an absolute path to a file under /proc or /sys eg.
+// "/sys/kernel/mm/transparent_hugepage/enabled". Runs of whitespace in values are compared as a single space, so the
+// expected value of a multi-value parameter like net.ipv4.ip_local_port_range should be eg. "32768 60999".
+func (kptc KernelParameterTestCase) WithValue(key string, expected string) KernelParameterTestCase {
+	return kptc.with(kernelParameter{key: key, expected: strings.Join(strings.Fields(expected), " ")})
+}
+
+// WithRange returns a copy of the KernelParameterTestCase that checks the integer value of key is between min and max
+// inclusive.
+func (kptc KernelParameterTestCase) WithRange(key string, min int64, max int64) KernelParameterTestCase {
+	return kptc.with(kernelParameter{key: key, min: &min, max: &max})
+}
+
+// WithMinimum returns a copy of the KernelParameterTestCase that checks the integer value of key is at least min.
+func (kptc KernelParameterTestCase) WithMinimum(key string, min int64) KernelParameterTestCase {
+	return kptc.with(kernelParameter{key: key, min: &min})
+}
+
+// WithMaximum returns a copy of the KernelParameterTestCase that checks the integer value of key is at most max.
+func (kptc KernelParameterTestCase) WithMaximum(key string, max int64) KernelParameterTestCase {
+	return kptc.with(kernelParameter{key: key, max: &max})
+}
+
+func (kptc KernelParameterTestCase) with(parameter kernelParameter) KernelParameterTestCase {
+	// copy the parameters so that test cases derived from the same KernelParameterTestCase do not share them
+	parameters := make([]kernelParameter, 0, len(kptc.parameters)+1)
+	kptc.parameters = append(append(parameters, kptc.parameters...), parameter)
+	return kptc
+}
+
+// Parameters returns the actual value of each of the checked parameters on each instance in result, keyed by instance id
+// and then parameter key.
+func (kptc KernelParameterTestCase) Parameters(result TestResult) map[string]map[string]string {
+	parameters := map[string]map[string]string{}
+	for _, v := range result.Invocations {
+		values := v.Values()
+		instanceParameters := map[string]string{}
+		for _, p := range kptc.parameters {
+			if value, ok := values[p.key]; ok {
+				instanceParameters[p.key] = value
+			}
+		}
+		parameters[v.InstanceId] = instanceParameters
+	}
+	return parameters
+}
+
+// NewKernelParameterTestCase is a constructor for KernelParameterTestCase type.
+// The parameters to check are added with the With methods eg.
+//
+//	tester.NewKernelParameterTestCase().
+//		WithValue("net.ipv4.ip_forward", "0").
+//		WithMinimum("vm.max_map_count", 262144)
+func NewKernelParameterTestCase() KernelParameterTestCase {
+	return KernelParameterTestCase{}
+}
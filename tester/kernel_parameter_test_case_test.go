@@ -0,0 +1,54 @@
+package tester
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"reflect"
+	"testing"
+)
+
+func TestKernelParameterTestCase(t *testing.T) {
+	testCase := NewKernelParameterTestCase().
+		WithValue("net.ipv4.ip_forward", "0").
+		WithMinimum("vm.max_map_count", 262144).
+		WithRange("/proc/sys/fs/file-max", 1024, 65536)
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`value=$(sysctl -n 'net.ipv4.ip_forward' 2>/dev/null | tr -s ' \t' '  '); echo 'net.ipv4.ip_forward'"=$value"`,
+			`if [ -z "$value" ]; then fail 'net.ipv4.ip_forward' 'not found'; elif ! { [ "$value" = '0' ]; }; then fail 'net.ipv4.ip_forward' 'is' "$value" 'expected' '0'; fi`,
+			`value=$(sysctl -n 'vm.max_map_count' 2>/dev/null | tr -s ' \t' '  '); echo 'vm.max_map_count'"=$value"`,
+			`if [ -z "$value" ]; then fail 'vm.max_map_count' 'not found'; elif ! { [ "$value" -ge 262144 ] 2>/dev/null; }; then fail 'vm.max_map_count' 'is' "$value" 'expected' 'at least 262144'; fi`,
+			`value=$(cat '/proc/sys/fs/file-max' 2>/dev/null | tr -s ' \t' '  '); echo '/proc/sys/fs/file-max'"=$value"`,
+			`if [ -z "$value" ]; then fail '/proc/sys/fs/file-max' 'not found'; elif ! { [ "$value" -ge 1024 ] 2>/dev/null && [ "$value" -le 65536 ] 2>/dev/null; }; then fail '/proc/sys/fs/file-max' 'is' "$value" 'expected' 'at least 1024 and at most 65536'; fi`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestKernelParameterTestCaseParameters(t *testing.T) {
+	testCase := NewKernelParameterTestCase().
+		WithValue("net.ipv4.ip_local_port_range", "32768	60999").
+		WithMinimum("vm.max_map_count", 262144)
+	result := TestResult{
+		Invocations: []InvocationResult{
+			{
+				InstanceId: "i-1",
+				Status:     types.CommandInvocationStatusFailed,
+				Output:     "net.ipv4.ip_local_port_range=32768 60999\nvm.max_map_count=65530\nFAIL: vm.max_map_count is 65530 expected at least 262144\n",
+			},
+		},
+	}
+	expected := map[string]map[string]string{
+		"i-1": {"net.ipv4.ip_local_port_range": "32768 60999", "vm.max_map_count": "65530"},
+	}
+	if a := testCase.Parameters(result); !reflect.DeepEqual(expected, a) {
+		t.Errorf("Expected %v, but got %v", expected, a)
+	}
+	if e, a := []string{"vm.max_map_count is 65530 expected at least 262144"}, result.Invocations[0].Failures(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected %v, but got %v", e, a)
+	}
+}
@@ -0,0 +1,128 @@
+package tester
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PrivateAddress may be used as the Address of a Listener to refer to the primary private IPv4 address of each target
+// instance, as the address is not known before hand.
+const PrivateAddress = "private"
+
+// Listener is a socket listening on a target instance.
+// ss reports sockets bound to both the IPv4 and IPv6 any-address with the address *, which matches an Address of either
+// 0.0.0.0 or ::.
+type Listener struct {
+	Protocol string // tcp or udp
+	Address  string // the bind address eg. 0.0.0.0, ::, 127.0.0.1 or PrivateAddress, any address if empty
+	Port     int    // the port
+	Process  string // the name of the process that owns the socket, any process if empty
+}
+
+func (l Listener) String() string {
+	return fmt.Sprintf("%s/%s/%d/%s", l.Protocol, l.Address, l.Port, l.Process)
+}
+
+// ListeningPortsTestCase configuration for a test that checks the sockets the target instances are listening on.
+// It complements TcpConnectionTestWithTagName, which tests outbound reachability, with an inside-out check of what an
+// instance exposes, eg. "only 22 and 8080 are listening, and 8080 only on the private interface".
+// The listeners found on each instance are available from the TestResult with Listeners.
+//
+// The test relies on the ss binary (iproute2) and hostname binary being installed on the target instances.
+type ListeningPortsTestCase struct {
+	runShellScript
+	expected        []Listener // the listeners that should be found
+	onlyProtocols   []string   // the protocols for which any listener not expected fails the test
+	includeLoopback bool       // whether listeners bound to loopback addresses are also checked for being expected
+}
+
+// normalises the output of ss to lines of: protocol address port process
+const listListenersCommand = `listeners=$(ss -ltunp | awk 'NR > 1 { addr = $5; port = addr; sub(/.*:/, "", port); sub(/:[^:]*$/, "", addr); sub(/%.*/, "", addr); gsub(/[][]/, "", addr); proc = ""; if (match($0, /users:\(\("[^"]*"/)) proc = substr($0, RSTART + 9, RLENGTH - 10); print $1, addr, port, proc }' | sort -u)`
+
+// matches a line of listeners against the expected listeners, encoded as protocol|address|port|process entries separated by ;
+// the wildcard address * of ss matches either of the any-addresses
+const matchListenerAwkFunction = `function matches(line_proto, line_addr, line_port, line_proc,    i, n, e, f) { n = split(expected, e, ";"); for (i = 1; i <= n; i++) { split(e[i], f, "|"); if (f[2] == "` + PrivateAddress + `") f[2] = private; if (line_addr == "*" && (f[2] == "0.0.0.0" || f[2] == "::")) f[2] = "*"; if (line_proto == f[1] && line_port == f[3] && (f[2] == "" || line_addr == f[2]) && (f[4] == "" || line_proc == f[4])) return 1 }; return 0 }`
+
+func (lptc ListeningPortsTestCase) buildCommandParameters() map[string][]string {
+	assertions := []string{
+		listListenersCommand,
+		`private=$(hostname -I | awk '{ print $1 }')`,
+		`echo "listeners=$(echo "$listeners" | awk 'NF { printf "%s%s/%s/%s/%s", sep, $1, $2, $3, $4; sep = " " }')"`,
+	}
+	for _, v := range lptc.expected {
+		assertions = append(assertions, fmt.Sprintf(
+			`echo "$listeners" | awk -v expected=%s -v private="$private" '%s matches($1, $2, $3, $4) { found = 1 } END { exit !found }' || fail 'no listener found for' %s`,
+			shellQuote(encodeListeners([]Listener{v})), matchListenerAwkFunction, shellQuote(v.String())))
+	}
+	if len(lptc.onlyProtocols) > 0 {
+		loopbackFilter := ` && $2 !~ /^(127\.|::1$)/`
+		if lptc.includeLoopback {
+			loopbackFilter = ""
+		}
+		assertions = append(assertions,
+			fmt.Sprintf(`unexpected=$(echo "$listeners" | awk -v expected=%s -v private="$private" -v protocols=%s '%s index(protocols, " " $1 " ")%s && !matches($1, $2, $3, $4) { print $1 "/" $2 "/" $3 "/" $4 }')`,
+				shellQuote(encodeListeners(lptc.expected)), shellQuote(" "+strings.Join(lptc.onlyProtocols, " ")+" "), matchListenerAwkFunction, loopbackFilter),
+			`[ -z "$unexpected" ] || fail 'unexpected listeners found' $unexpected`,
+		)
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+func encodeListeners(listeners []Listener) string {
+	var entries []string
+	for _, v := range listeners {
+		entries = append(entries, strings.Join([]string{v.Protocol, v.Address, strconv.Itoa(v.Port), v.Process}, "|"))
+	}
+	return strings.Join(entries, ";")
+}
+
+// WithNoOtherListeners returns a copy of the ListeningPortsTestCase that fails if an instance is listening on any socket
+// for protocols that does not match one of the expected listeners. If no protocols are given, only tcp sockets are checked.
+// Sockets bound to loopback addresses are ignored, as they are not reachable from other hosts, unless
+// WithLoopbackIncluded is also used.
+func (lptc ListeningPortsTestCase) WithNoOtherListeners(protocols ...string) ListeningPortsTestCase {
+	if len(protocols) == 0 {
+		protocols = []string{"tcp"}
+	}
+	lptc.onlyProtocols = protocols
+	return lptc
+}
+
+// WithLoopbackIncluded returns a copy of the ListeningPortsTestCase that also fails WithNoOtherListeners for
+// sockets bound to loopback addresses.
+func (lptc ListeningPortsTestCase) WithLoopbackIncluded() ListeningPortsTestCase {
+	lptc.includeLoopback = true
+	return lptc
+}
+
+// Listeners returns the listeners found on each instance in result, keyed by instance id.
+func (lptc ListeningPortsTestCase) Listeners(result TestResult) map[string][]Listener {
+	listeners := map[string][]Listener{}
+	for _, v := range result.Invocations {
+		var found []Listener
+		for _, entry := range strings.Fields(v.Values()["listeners"]) {
+			fields := strings.SplitN(entry, "/", 4)
+			if len(fields) != 4 {
+				continue
+			}
+			port, _ := strconv.Atoi(fields[2])
+			found = append(found, Listener{Protocol: fields[0], Address: fields[1], Port: port, Process: fields[3]})
+		}
+		listeners[v.InstanceId] = found
+	}
+	return listeners
+}
+
+// NewListeningPortsTestCase is a constructor for ListeningPortsTestCase type.
+// expected are the listeners that should be found on each of the target instances eg.
+//
+//	tester.NewListeningPortsTestCase(
+//	    tester.Listener{Protocol: "tcp", Port: 22, Process: "sshd"},
+//	    tester.Listener{Protocol: "tcp", Port: 8080, Address: tester.PrivateAddress},
+//	).WithNoOtherListeners()
+func NewListeningPortsTestCase(expected ...Listener) ListeningPortsTestCase {
+	return ListeningPortsTestCase{
+		expected: expected,
+	}
+}
@@ -0,0 +1,90 @@
+package tester
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListeningPortsTestCase(t *testing.T) {
+	testCase := NewListeningPortsTestCase(
+		Listener{Protocol: "tcp", Port: 22, Process: "sshd"},
+		Listener{Protocol: "tcp", Port: 8080, Address: PrivateAddress},
+	).WithNoOtherListeners()
+	commands := testCase.buildCommandParameters()["commands"]
+
+	expectedAssertions := []string{
+		`echo "$listeners" | awk -v expected='tcp||22|sshd' -v private="$private" '` + matchListenerAwkFunction + ` matches($1, $2, $3, $4) { found = 1 } END { exit !found }' || fail 'no listener found for' 'tcp//22/sshd'`,
+		`echo "$listeners" | awk -v expected='tcp|private|8080|' -v private="$private" '` + matchListenerAwkFunction + ` matches($1, $2, $3, $4) { found = 1 } END { exit !found }' || fail 'no listener found for' 'tcp/private/8080/'`,
+		`unexpected=$(echo "$listeners" | awk -v expected='tcp||22|sshd;tcp|private|8080|' -v private="$private" -v protocols=' tcp ' '` + matchListenerAwkFunction + ` index(protocols, " " $1 " ") && $2 !~ /^(127\.|::1$)/ && !matches($1, $2, $3, $4) { print $1 "/" $2 "/" $3 "/" $4 }')`,
+	}
+	assertCommandsContain(t, commands, expectedAssertions...)
+}
+
+// fakeSs prints the output of ss -ltunp for sshd listening on the IPv4 and IPv6 any-addresses, which ss reports as *.
+const fakeSs = `#!/bin/sh
+echo 'Netid State  Recv-Q Send-Q Local Address:Port Peer Address:Port Process'
+echo 'tcp   LISTEN 0      128                *:22              *:*    users:(("sshd",pid=812,fd=3))'
+echo 'tcp   LISTEN 0      128         10.0.0.5:8080      0.0.0.0:*    users:(("java",pid=901,fd=40))'
+`
+
+func TestListeningPortsTestCaseWildcardAddress(t *testing.T) {
+	if _, err := exec.LookPath("awk"); err != nil {
+		t.Skip("awk is not installed")
+	}
+	dir, err := ioutil.TempDir("", "listening-ports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "ss"), []byte(fakeSs), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "hostname"), []byte("#!/bin/sh\necho 10.0.0.5\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		caseName       string
+		testCase       ListeningPortsTestCase
+		expectedPassed bool
+	}{
+		{"IPv4 any-address", NewListeningPortsTestCase(Listener{Protocol: "tcp", Address: "0.0.0.0", Port: 22, Process: "sshd"}), true},
+		{"IPv6 any-address", NewListeningPortsTestCase(Listener{Protocol: "tcp", Address: "::", Port: 22}), true},
+		{"loopback address", NewListeningPortsTestCase(Listener{Protocol: "tcp", Address: "127.0.0.1", Port: 22}), false},
+		{"no other listeners", NewListeningPortsTestCase(
+			Listener{Protocol: "tcp", Address: "::", Port: 22},
+			Listener{Protocol: "tcp", Address: PrivateAddress, Port: 8080},
+		).WithNoOtherListeners(), true},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			cmd := exec.Command("sh", "-c", strings.Join(c.testCase.buildCommandParameters()["commands"], "\n"))
+			cmd.Env = append(os.Environ(), "PATH="+dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+			output, err := cmd.CombinedOutput()
+			if e, a := c.expectedPassed, err == nil; e != a {
+				t.Errorf("Expected passed to be %v, but got %v with output %s", e, a, output)
+			}
+		})
+	}
+}
+
+func TestListeningPortsTestCaseListeners(t *testing.T) {
+	result := TestResult{
+		Invocations: []InvocationResult{
+			{InstanceId: "i-1", Status: types.CommandInvocationStatusSuccess, Output: "listeners=tcp/0.0.0.0/22/sshd udp/127.0.0.1/323/\n"},
+			{InstanceId: "i-2", Status: types.CommandInvocationStatusSuccess, Output: "listeners=\n"},
+		},
+	}
+	expected := map[string][]Listener{
+		"i-1": {{Protocol: "tcp", Address: "0.0.0.0", Port: 22, Process: "sshd"}, {Protocol: "udp", Address: "127.0.0.1", Port: 323}},
+		"i-2": nil,
+	}
+	if a := NewListeningPortsTestCase().Listeners(result); !reflect.DeepEqual(expected, a) {
+		t.Errorf("Expected %v, but got %v", expected, a)
+	}
+}
@@ -0,0 +1,138 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Logger is the interface of the logger the tester reports its progress to, eg. each poll for the results of a test
+// command. It has the methods of *slog.Logger, so one may be used as a Logger. args are alternating keys and values,
+// eg. "commandId", commandId, "attempt", 2.
+//
+// Use RetryConfig.WithLogger to set the Logger of a test. By default the tester logs with t.Logf, so that the output
+// sits under the test or subtest being run, and is only shown by go test -v or for failed tests.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// LogLevel is the severity of a log message, the values are those of slog.Level.
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = -4
+	LevelInfo  LogLevel = 0
+	LevelWarn  LogLevel = 4
+	LevelError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+	switch {
+	case l < LevelInfo:
+		return "DEBUG"
+	case l < LevelWarn:
+		return "INFO"
+	case l < LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// NewTestLogger returns a Logger that logs messages of level and above with t.Logf.
+func NewTestLogger(t *testing.T, level LogLevel) Logger {
+	return printfLogger{
+		printf: func(format string, args ...interface{}) {
+			t.Helper()
+			t.Logf(format, args...)
+		},
+		helper: t.Helper,
+		level:  level,
+	}
+}
+
+// NewWriterLogger returns a Logger that writes messages of level and above to w, one per line, eg. for command line
+// tools that run tests outside of go test. It is safe for concurrent use.
+func NewWriterLogger(w io.Writer, level LogLevel) Logger {
+	var mutex sync.Mutex
+	return printfLogger{
+		printf: func(format string, args ...interface{}) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			fmt.Fprintf(w, format+"\n", args...)
+		},
+		helper: func() {},
+		level:  level,
+	}
+}
+
+// printfLogger formats messages as the level, the message and then each key=value pair, eg.
+// DEBUG polling for invocation results commandId=1234 attempt=2 pending=1
+type printfLogger struct {
+	printf func(format string, args ...interface{})
+	helper func() // marks the calling function as a test helper, so that t.Logf reports the line that logged
+	level  LogLevel
+}
+
+func (pl printfLogger) Debug(msg string, args ...interface{}) {
+	pl.helper()
+	pl.log(LevelDebug, msg, args)
+}
+
+func (pl printfLogger) Info(msg string, args ...interface{}) {
+	pl.helper()
+	pl.log(LevelInfo, msg, args)
+}
+
+func (pl printfLogger) Warn(msg string, args ...interface{}) {
+	pl.helper()
+	pl.log(LevelWarn, msg, args)
+}
+
+func (pl printfLogger) Error(msg string, args ...interface{}) {
+	pl.helper()
+	pl.log(LevelError, msg, args)
+}
+
+func (pl printfLogger) log(level LogLevel, msg string, args []interface{}) {
+	pl.helper()
+	if level < pl.level {
+		return
+	}
+	pl.printf("%s", formatLogLine(level, msg, args))
+}
+
+func formatLogLine(level LogLevel, msg string, args []interface{}) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s %s", level, msg)
+	for i := 0; i < len(args); i += 2 {
+		// a key without a value is logged as the value of a missing key, as slog does
+		key, value := "!BADKEY", args[i]
+		if i+1 < len(args) {
+			key, value = fmt.Sprint(args[i]), args[i+1]
+		}
+		formatted := fmt.Sprint(value)
+		if formatted == "" || strings.ContainsAny(formatted, " \t\n\"=") {
+			formatted = fmt.Sprintf("%q", formatted)
+		}
+		fmt.Fprintf(&builder, " %s=%s", key, formatted)
+	}
+	return builder.String()
+}
+
+// discardLogger is the Logger of tests run without a *testing.T and without a Logger.
+type discardLogger struct {
+}
+
+func (dl discardLogger) Debug(msg string, args ...interface{}) {}
+
+func (dl discardLogger) Info(msg string, args ...interface{}) {}
+
+func (dl discardLogger) Warn(msg string, args ...interface{}) {}
+
+func (dl discardLogger) Error(msg string, args ...interface{}) {}
@@ -0,0 +1,70 @@
+package tester
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriterLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := NewWriterLogger(&buffer, LevelInfo)
+	logger.Debug("not logged", "commandId", "1234")
+	logger.Info("sent test command", "commandId", "1234", "attempt", 2)
+	logger.Warn("max retries exceeded", "error", errors.New("no invocations found"), "empty", "")
+	logger.Error("odd args", "commandId")
+	expected := `INFO sent test command commandId=1234 attempt=2
+WARN max retries exceeded error="no invocations found" empty=""
+ERROR odd args !BADKEY=commandId
+`
+	if e, a := expected, buffer.String(); e != a {
+		t.Errorf("Expected the log to be \n%s, but got \n%s", e, a)
+	}
+}
+
+type recordingLogger struct {
+	discardLogger
+	messages *[]string
+}
+
+func (rl recordingLogger) Debug(msg string, args ...interface{}) {
+	*rl.messages = append(*rl.messages, formatLogLine(LevelDebug, msg, args))
+}
+
+func (rl recordingLogger) Warn(msg string, args ...interface{}) {
+	*rl.messages = append(*rl.messages, formatLogLine(LevelWarn, msg, args))
+}
+
+func TestRetryLogging(t *testing.T) {
+	var messages []string
+	logger := recordingLogger{messages: &messages}
+	_, err := retry(logger, "Poll", 1, time.Nanosecond, func() (interface{}, error) {
+		return nil, invocationsIncompleteError{pending: 2}
+	}, "commandId", "1234")
+	if _, isMaxRetriesExceeded := err.(maxRetriesExceededError); !isMaxRetriesExceeded {
+		t.Errorf("Expected max retries exceeded, but got %v", err)
+	}
+	expected := []string{
+		`DEBUG Poll will try again commandId=1234 attempt=1 pending=2 error="command invocations pending, inprogress or delayed" wait=1ns`,
+		`DEBUG Poll will try again commandId=1234 attempt=2 pending=2 error="command invocations pending, inprogress or delayed" wait=1ns`,
+		`WARN Poll max retries exceeded commandId=1234 error="command invocations pending, inprogress or delayed"`,
+	}
+	if e, a := expected, messages; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the messages to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestRetryConfigLoggerFor(t *testing.T) {
+	logger := recordingLogger{messages: &[]string{}}
+	if _, isPrintfLogger := NewRetryDefaultConfig().loggerFor(t).(printfLogger); !isPrintfLogger {
+		t.Errorf("Expected a logger for t by default")
+	}
+	if _, isDiscardLogger := NewRetryDefaultConfig().loggerFor(nil).(discardLogger); !isDiscardLogger {
+		t.Errorf("Expected logging to be discarded without t")
+	}
+	if e, a := logger, NewRetryDefaultConfig().WithLogger(logger).loggerFor(t); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the logger set to be used")
+	}
+}
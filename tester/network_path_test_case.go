@@ -0,0 +1,185 @@
+package tester
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RouteTestCase configuration for a test that checks the route the target instances use to reach a destination, as
+// chosen by the kernel with ip route get, eg. that traffic to on-premises ranges leaves through a VPN appliance or a
+// secondary interface.
+// The route is reported as name=value pairs in the output, available with InvocationResult.Values.
+type RouteTestCase struct {
+	runShellScript
+	destination string // the IPv4 address or hostname of the destination
+	gateway     string // the expected next hop, not checked if empty
+	device      string // the expected outgoing interface eg. eth1, not checked if empty
+	source      string // the expected source address, not checked if empty
+}
+
+func (rtc RouteTestCase) buildCommandParameters() map[string][]string {
+	destination := shellQuote(rtc.destination)
+	assertions := []string{
+		fmt.Sprintf(`address=$(getent ahostsv4 %s | awk 'NR == 1 { print $1 }')`, destination),
+		fmt.Sprintf(`if [ -z "$address" ]; then echo "FAIL: cannot resolve "%s; exit 1; fi`, destination),
+		`if ! route=$(ip -4 route get "$address" 2>&1); then echo "FAIL: no route to $address: $route"; exit 1; fi`,
+		`field() { echo "$route" | awk -v name="$1" 'NR == 1 { for (i = 1; i < NF; i++) if ($i == name) print $(i + 1) }'; }`,
+		`gateway=$(field via); device=$(field dev); source=$(field src)`,
+		`echo "address=$address"; echo "gateway=$gateway"; echo "device=$device"; echo "source=$source"`,
+	}
+	if rtc.gateway != "" {
+		assertions = append(assertions, expectEqual("gateway", "$gateway", rtc.gateway))
+	}
+	if rtc.device != "" {
+		assertions = append(assertions, expectEqual("device", "$device", rtc.device))
+	}
+	if rtc.source != "" {
+		assertions = append(assertions, expectEqual("source", "$source", rtc.source))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// WithGateway returns a copy of the RouteTestCase that checks the route goes via the next hop gateway eg. "10.0.0.1".
+func (rtc RouteTestCase) WithGateway(gateway string) RouteTestCase {
+	rtc.gateway = gateway
+	return rtc
+}
+
+// WithDevice returns a copy of the RouteTestCase that checks the route leaves through the interface device eg. "eth1".
+func (rtc RouteTestCase) WithDevice(device string) RouteTestCase {
+	rtc.device = device
+	return rtc
+}
+
+// WithSource returns a copy of the RouteTestCase that checks the route uses the source address source.
+func (rtc RouteTestCase) WithSource(source string) RouteTestCase {
+	rtc.source = source
+	return rtc
+}
+
+// NewRouteTestCase is a constructor for RouteTestCase type.
+// destination should be an IPv4 address or a hostname, which is resolved on the instance to its first IPv4 address.
+// By default the test only checks that there is a route to the destination, further checks are added with the With
+// methods.
+func NewRouteTestCase(destination string) RouteTestCase {
+	return RouteTestCase{
+		destination: destination,
+	}
+}
+
+// PathMtuTestCase configuration for a test that checks the path MTU from the target instances to an endpoint, by sending
+// pings of the MTU size with the don't fragment bit set, eg. to catch jumbo frames being silently dropped over a transit
+// gateway or VPN, which only breaks large transfers.
+// ICMP echo requests must be allowed to the endpoint for the test to pass. The outcome of each probed size is reported
+// as name=value pairs in the output eg. mtu_1500=ok, available with InvocationResult.Values.
+//
+// The test relies on the iputils ping binary being installed on the target instances.
+type PathMtuTestCase struct {
+	runShellScript
+	endpoint string // the IPv4 address or hostname to ping
+	mtu      int    // the size of the IP packets that should reach the endpoint
+	exact    bool   // whether to also check that larger packets do not reach the endpoint
+}
+
+// the size of the IPv4 and ICMP headers, which ping does not count in its payload size
+const pingHeaderSize = 28
+
+func (pmtc PathMtuTestCase) buildCommandParameters() map[string][]string {
+	endpoint := shellQuote(pmtc.endpoint)
+	probe := func(mtu int) string {
+		return fmt.Sprintf(`if ping -n -c 3 -W 2 -M do -s %d %s >/dev/null 2>&1; then mtu_%d=ok; else mtu_%d=dropped; fi; echo "mtu_%d=$mtu_%d"`,
+			mtu-pingHeaderSize, endpoint, mtu, mtu, mtu, mtu)
+	}
+	assertions := []string{
+		probe(pmtc.mtu),
+		fmt.Sprintf(`[ "$mtu_%d" = ok ] || fail 'packets of %d bytes with DF set do not reach' %s`, pmtc.mtu, pmtc.mtu, endpoint),
+	}
+	if pmtc.exact {
+		assertions = append(assertions,
+			probe(pmtc.mtu+1),
+			fmt.Sprintf(`[ "$mtu_%d" = dropped ] || fail 'packets of %d bytes with DF set reach' %s 'expected path MTU of %d'`,
+				pmtc.mtu+1, pmtc.mtu+1, endpoint, pmtc.mtu),
+		)
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// WithExact returns a copy of the PathMtuTestCase that also checks packets one byte larger than the MTU do not reach the
+// endpoint, ie. that the path MTU is exactly the MTU.
+func (pmtc PathMtuTestCase) WithExact() PathMtuTestCase {
+	pmtc.exact = true
+	return pmtc
+}
+
+// NewPathMtuTestCase is a constructor for PathMtuTestCase type.
+// endpoint should be the IPv4 address or hostname to check the path to. mtu is the size in bytes of the IP packets that
+// should reach endpoint without fragmentation eg. 1500, or 9001 within a VPC.
+func NewPathMtuTestCase(endpoint string, mtu int) PathMtuTestCase {
+	return PathMtuTestCase{
+		endpoint: endpoint,
+		mtu:      mtu,
+	}
+}
+
+// EgressIpTestCase configuration for a test that checks the public IP address the target instances egress to the
+// internet from, as seen by an echo endpoint, eg. to prove that private subnets go through the NAT gateway with the
+// elastic IP that partners have allow-listed.
+// The egress IP of each instance is available from the TestResult with EgressIps.
+//
+// The test relies on the curl binary being installed on the target instances.
+type EgressIpTestCase struct {
+	runShellScript
+	expected         []string // the allowed egress IPs eg. the elastic IPs of the NAT gateways of a VPC
+	echoEndpoint     string   // the url of an endpoint that responds with the IP address of the caller
+	timeoutInSeconds int      // the time to wait for the echo endpoint to respond
+}
+
+// DefaultEchoEndpoint is the echo endpoint used by an EgressIpTestCase unless configured with WithEchoEndpoint.
+const DefaultEchoEndpoint = "https://checkip.amazonaws.com"
+
+func (eitc EgressIpTestCase) buildCommandParameters() map[string][]string {
+	assertions := []string{
+		fmt.Sprintf(`egress_ip=$(curl -s --max-time %d %s | tr -d ' \r\n')`, eitc.timeoutInSeconds, shellQuote(eitc.echoEndpoint)),
+		`echo "egress_ip=$egress_ip"`,
+		fmt.Sprintf(`if [ -z "$egress_ip" ]; then echo "FAIL: no response from "%s; exit 1; fi`, shellQuote(eitc.echoEndpoint)),
+	}
+	if len(eitc.expected) > 0 {
+		expected := strings.Join(eitc.expected, " ")
+		assertions = append(assertions, fmt.Sprintf(`case " "%s" " in *" $egress_ip "*) ;; *) fail 'egress IP is' "$egress_ip" 'expected one of' %s ;; esac`,
+			shellQuote(expected), shellQuote(expected)))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// WithEchoEndpoint returns a copy of the EgressIpTestCase that asks url for the egress IP, instead of
+// DefaultEchoEndpoint. The endpoint should respond with the IP address of the caller as plain text.
+func (eitc EgressIpTestCase) WithEchoEndpoint(url string) EgressIpTestCase {
+	eitc.echoEndpoint = url
+	return eitc
+}
+
+// WithTimeout returns a copy of the EgressIpTestCase that waits timeoutInSeconds for the echo endpoint to respond.
+func (eitc EgressIpTestCase) WithTimeout(timeoutInSeconds int) EgressIpTestCase {
+	eitc.timeoutInSeconds = timeoutInSeconds
+	return eitc
+}
+
+// EgressIps returns the egress IP seen by the echo endpoint for each instance in result, keyed by instance id.
+func (eitc EgressIpTestCase) EgressIps(result TestResult) map[string]string {
+	ips := map[string]string{}
+	for _, v := range result.Invocations {
+		ips[v.InstanceId] = v.Values()["egress_ip"]
+	}
+	return ips
+}
+
+// NewEgressIpTestCase is a constructor for EgressIpTestCase type.
+// expected are the public IP addresses the instances may egress from eg. the elastic IPs of the NAT gateways in each
+// availability zone. If none are given the test only checks the instances can reach the echo endpoint.
+func NewEgressIpTestCase(expected ...string) EgressIpTestCase {
+	return EgressIpTestCase{
+		expected:         expected,
+		echoEndpoint:     DefaultEchoEndpoint,
+		timeoutInSeconds: 5,
+	}
+}
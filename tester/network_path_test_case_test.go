@@ -0,0 +1,91 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRouteTestCase(t *testing.T) {
+	testCase := NewRouteTestCase("10.100.0.10").
+		WithGateway("10.0.1.1").
+		WithDevice("eth1").
+		WithSource("10.0.1.20")
+	assertCommandsContain(t, testCase.buildCommandParameters()["commands"],
+		`address=$(getent ahostsv4 '10.100.0.10' | awk 'NR == 1 { print $1 }')`,
+		`if ! route=$(ip -4 route get "$address" 2>&1); then echo "FAIL: no route to $address: $route"; exit 1; fi`,
+		`[ "$gateway" = '10.0.1.1' ] || fail 'gateway is' "$gateway" 'expected' '10.0.1.1'`,
+		`[ "$device" = 'eth1' ] || fail 'device is' "$device" 'expected' 'eth1'`,
+		`[ "$source" = '10.0.1.20' ] || fail 'source is' "$source" 'expected' '10.0.1.20'`,
+	)
+}
+
+func TestPathMtuTestCase(t *testing.T) {
+	cases := []struct {
+		caseName         string
+		testCase         PathMtuTestCase
+		expectedCommands []string
+	}{
+		{
+			"mtu",
+			NewPathMtuTestCase("10.100.0.10", 1500),
+			[]string{
+				"rc=0",
+				`fail() { echo "FAIL: $*"; rc=1; }`,
+				`if ping -n -c 3 -W 2 -M do -s 1472 '10.100.0.10' >/dev/null 2>&1; then mtu_1500=ok; else mtu_1500=dropped; fi; echo "mtu_1500=$mtu_1500"`,
+				`[ "$mtu_1500" = ok ] || fail 'packets of 1500 bytes with DF set do not reach' '10.100.0.10'`,
+				"exit $rc",
+			},
+		},
+		{
+			"exact mtu",
+			NewPathMtuTestCase("10.100.0.10", 1500).WithExact(),
+			[]string{
+				"rc=0",
+				`fail() { echo "FAIL: $*"; rc=1; }`,
+				`if ping -n -c 3 -W 2 -M do -s 1472 '10.100.0.10' >/dev/null 2>&1; then mtu_1500=ok; else mtu_1500=dropped; fi; echo "mtu_1500=$mtu_1500"`,
+				`[ "$mtu_1500" = ok ] || fail 'packets of 1500 bytes with DF set do not reach' '10.100.0.10'`,
+				`if ping -n -c 3 -W 2 -M do -s 1473 '10.100.0.10' >/dev/null 2>&1; then mtu_1501=ok; else mtu_1501=dropped; fi; echo "mtu_1501=$mtu_1501"`,
+				`[ "$mtu_1501" = dropped ] || fail 'packets of 1501 bytes with DF set reach' '10.100.0.10' 'expected path MTU of 1500'`,
+				"exit $rc",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if e, a := c.expectedCommands, c.testCase.buildCommandParameters()["commands"]; !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the commands to be \n%v, but got \n%v", e, a)
+			}
+		})
+	}
+}
+
+func TestEgressIpTestCase(t *testing.T) {
+	testCase := NewEgressIpTestCase("52.0.0.1", "52.0.0.2")
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`egress_ip=$(curl -s --max-time 5 'https://checkip.amazonaws.com' | tr -d ' \r\n')`,
+			`echo "egress_ip=$egress_ip"`,
+			`if [ -z "$egress_ip" ]; then echo "FAIL: no response from "'https://checkip.amazonaws.com'; exit 1; fi`,
+			`case " "'52.0.0.1 52.0.0.2'" " in *" $egress_ip "*) ;; *) fail 'egress IP is' "$egress_ip" 'expected one of' '52.0.0.1 52.0.0.2' ;; esac`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestEgressIpTestCaseEgressIps(t *testing.T) {
+	result := TestResult{
+		Invocations: []InvocationResult{
+			{InstanceId: "i-1", Output: "egress_ip=52.0.0.1\n"},
+			{InstanceId: "i-2", Output: "FAIL: no response from https://checkip.amazonaws.com\n"},
+		},
+	}
+	expected := map[string]string{"i-1": "52.0.0.1", "i-2": ""}
+	if e, a := expected, NewEgressIpTestCase().EgressIps(result); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the egress ips to be %v, but got %v", e, a)
+	}
+}
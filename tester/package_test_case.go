@@ -0,0 +1,191 @@
+package tester
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PackageTestCase configuration for a test that checks a package is installed, or absent, on the target instances, and
+// that the installed version meets a version constraint, eg. to gate promotions on patched openssl or glibc versions.
+// On linux the package manager is detected from dpkg, rpm and apk. On windows the installed programs are checked.
+// The installed version of the package on each instance is available from the TestResult with Versions.
+//
+// On dpkg instances versions are compared by dpkg --compare-versions. Otherwise versions are compared by their epoch,
+// eg. the 1 of 1:1.1.1f, and then segment by segment with numbers compared numerically (as sort -V does on linux, and as
+// System.Version does on windows), which matches semver for plain versions.
+type PackageTestCase struct {
+	platformScript
+	name        string              // the name of the package, or the display name of the program on windows
+	absent      bool                // check that the package is not installed
+	constraints []versionConstraint // the constraints the installed version should meet
+	err         error               // the error parsing the constraints, reported by Validate
+}
+
+type versionConstraint struct {
+	operator string // one of =, !=, <, <=, >, >=
+	version  string
+}
+
+func (vc versionConstraint) String() string {
+	return vc.operator + " " + vc.version
+}
+
+// the test operator for the result of comparing the installed version to the constraint version, as -1, 0 or 1
+var versionConstraintTestOperators = map[string]string{
+	"=":  "-eq",
+	"!=": "-ne",
+	"<":  "-lt",
+	"<=": "-le",
+	">":  "-gt",
+	">=": "-ge",
+}
+
+// versionOperatorCharacters are the characters of the supported operators, and of the operators of other constraint
+// syntaxes eg. ~> and ^, which are rejected rather than taken as part of the version.
+const versionOperatorCharacters = "<>=!~^"
+
+// parseVersionConstraints parses a comma separated list of constraints eg. ">= 1.1.1k, < 3.0".
+// A version without an operator is an exact match.
+func parseVersionConstraints(constraints string) ([]versionConstraint, error) {
+	var parsed []versionConstraint
+	for _, constraint := range strings.Split(constraints, ",") {
+		v := strings.TrimSpace(constraint)
+		if v == "" {
+			continue
+		}
+		operator := "="
+		// check the two character operators first, as they start with the one character operators
+		for _, candidate := range []string{">=", "<=", "!=", ">", "<", "="} {
+			if strings.HasPrefix(v, candidate) {
+				operator = candidate
+				v = strings.TrimSpace(strings.TrimPrefix(v, candidate))
+				break
+			}
+		}
+		if v == "" || strings.ContainsAny(v[:1], versionOperatorCharacters) {
+			return nil, fmt.Errorf("invalid version constraint %q, expected an operator (=, !=, <, <=, >, >=) and a version", constraint)
+		}
+		parsed = append(parsed, versionConstraint{operator: operator, version: v})
+	}
+	return parsed, nil
+}
+
+func (pktc PackageTestCase) buildCommandParameters() map[string][]string {
+	if pktc.windows {
+		return shellCommandParameters(pktc.buildPowerShellScript())
+	}
+	if pktc.err != nil {
+		// fail rather than check less than the test case says
+		return shellCommandParameters([]string{fmt.Sprintf("echo %s; exit 1", shellQuote("FAIL: "+pktc.err.Error()))})
+	}
+	name := shellQuote(pktc.name)
+	assertions := []string{
+		`version=""`,
+		fmt.Sprintf(`if command -v dpkg-query >/dev/null 2>&1; then manager=dpkg; version=$(dpkg-query -W -f '${Status} ${Version}\n' %s 2>/dev/null | awk '$3 == "installed" { print $4 }')`, name),
+		fmt.Sprintf(`elif command -v rpm >/dev/null 2>&1; then manager=rpm; rpm -q --quiet %s && version=$(rpm -q --qf '%%|EPOCH?{%%{EPOCH}:}|%%{VERSION}-%%{RELEASE}\n' %s | sort -V | tail -1)`, name, name),
+		fmt.Sprintf(`elif command -v apk >/dev/null 2>&1; then manager=apk; apk info -e %s >/dev/null && version=$(apk list -I %s 2>/dev/null | awk 'NR == 1 { print $1 }') && version=${version#%s-}`, name, name, name),
+		`else echo "FAIL: no supported package manager found"; exit 1; fi`,
+		`echo "manager=$manager"; echo "version=$version"`,
+	}
+	if pktc.absent {
+		assertions = append(assertions, fmt.Sprintf(`[ -z "$version" ] || fail 'package' %s 'is installed with version' "$version"`, name))
+		return shellCommandParameters(assertionScript(assertions...))
+	}
+	assertions = append(assertions,
+		fmt.Sprintf(`if [ -z "$version" ]; then echo "FAIL: package "%s" is not installed"; exit 1; fi`, name),
+		`epoch() { case "$1" in *:*) case "${1%%:*}" in ''|*[!0-9]*) echo 0 ;; *) echo "${1%%:*}" ;; esac ;; *) echo 0 ;; esac; }`,
+		`upstream() { case "$1" in *:*) case "${1%%:*}" in ''|*[!0-9]*) echo "$1" ;; *) echo "${1#*:}" ;; esac ;; *) echo "$1" ;; esac; }`,
+		`vercmp() {`,
+		`  if [ "$manager" = dpkg ]; then`,
+		`    if dpkg --compare-versions "$1" lt "$2"; then echo -1; elif dpkg --compare-versions "$1" eq "$2"; then echo 0; else echo 1; fi; return`,
+		`  fi`,
+		`  ea=$(epoch "$1"); eb=$(epoch "$2"); a=$(upstream "$1"); b=$(upstream "$2")`,
+		`  if [ "$ea" -ne "$eb" ]; then if [ "$ea" -lt "$eb" ]; then echo -1; else echo 1; fi`,
+		`  elif [ "$a" = "$b" ]; then echo 0`,
+		`  elif [ "$(printf '%s\n%s\n' "$a" "$b" | sort -V | head -1)" = "$a" ]; then echo -1`,
+		`  else echo 1; fi`,
+		`}`,
+	)
+	for _, v := range pktc.constraints {
+		assertions = append(assertions, fmt.Sprintf(`[ "$(vercmp "$version" %s)" %s 0 ] || fail 'version' "$version" 'does not satisfy' %s`,
+			shellQuote(v.version), versionConstraintTestOperators[v.operator], shellQuote(v.String())))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+func (pktc PackageTestCase) buildPowerShellScript() []string {
+	if pktc.err != nil {
+		return []string{fmt.Sprintf("Write-Output %s; exit 1", powerShellQuote("FAIL: "+pktc.err.Error()))}
+	}
+	name := powerShellQuote(pktc.name)
+	assertions := []string{
+		`$uninstallKeys = 'HKLM:\Software\Microsoft\Windows\CurrentVersion\Uninstall\*', 'HKLM:\Software\WOW6432Node\Microsoft\Windows\CurrentVersion\Uninstall\*'`,
+		fmt.Sprintf(`$program = Get-ItemProperty $uninstallKeys -ErrorAction SilentlyContinue | Where-Object { $_.DisplayName -eq %s } | Select-Object -First 1`, name),
+		`$version = "$($program.DisplayVersion)"`,
+		`Write-Output "manager=programs"; Write-Output "version=$version"`,
+	}
+	if pktc.absent {
+		assertions = append(assertions, fmt.Sprintf(`if ($program) { Fail "package" %s "is installed with version" $version }`, name))
+		return powerShellAssertionScript(assertions...)
+	}
+	assertions = append(assertions,
+		fmt.Sprintf(`if (-not $program) { Write-Output ("FAIL: package " + %s + " is not installed"); exit 1 }`, name),
+		`function Compare-Version($a, $b) { $va = $null; $vb = $null; if ([version]::TryParse($a, [ref]$va) -and [version]::TryParse($b, [ref]$vb)) { return [math]::Sign($va.CompareTo($vb)) }; return [math]::Sign([string]::Compare($a, $b)) }`,
+	)
+	for _, v := range pktc.constraints {
+		assertions = append(assertions, fmt.Sprintf(`if (-not ((Compare-Version $version %s) %s 0)) { Fail "version" $version "does not satisfy" %s }`,
+			powerShellQuote(v.version), versionConstraintTestOperators[v.operator], powerShellQuote(v.String())))
+	}
+	return powerShellAssertionScript(assertions...)
+}
+
+// WithVersionConstraint returns a copy of the PackageTestCase that checks the installed version meets constraints.
+// constraints is a comma separated list of an operator (=, !=, <, <=, >, >=) and a version eg. ">= 1.0.2k-24, < 1.1".
+// A version without an operator must match the installed version exactly. Other operators, eg. ~> or ==, are an error
+// reported by Validate, and fail the test on every instance.
+// On linux the installed version is reported as [epoch:]version-release for rpm packages, eg. 1.0.2k-24.amzn2.0.4, and
+// as [epoch:]upstream_version[-debian_revision] for dpkg packages, eg. 1:1.1.1f-1ubuntu2. A constraint version without an
+// epoch has epoch 0.
+func (pktc PackageTestCase) WithVersionConstraint(constraints string) PackageTestCase {
+	pktc.constraints, pktc.err = parseVersionConstraints(constraints)
+	return pktc
+}
+
+// Validate returns the error parsing the version constraints of the PackageTestCase, or nil if they are valid.
+func (pktc PackageTestCase) Validate() error {
+	return pktc.err
+}
+
+// WithWindows returns a copy of the PackageTestCase that checks the installed programs of windows instances via PowerShell.
+// The name should then be the display name of the program, as shown in Programs and Features.
+func (pktc PackageTestCase) WithWindows() PackageTestCase {
+	pktc.windows = true
+	return pktc
+}
+
+// Versions returns the installed version of the package on each instance in result, keyed by instance id.
+// The version is empty for instances the package is not installed on.
+func (pktc PackageTestCase) Versions(result TestResult) map[string]string {
+	versions := map[string]string{}
+	for _, v := range result.Invocations {
+		versions[v.InstanceId] = v.Values()["version"]
+	}
+	return versions
+}
+
+// NewPackageTestCase is a constructor for PackageTestCase type.
+// name should be the name of the package as known to the package manager of the target instances eg. "openssl".
+// By default the test checks that any version of the package is installed.
+func NewPackageTestCase(name string) PackageTestCase {
+	return PackageTestCase{
+		name: name,
+	}
+}
+
+// NewAbsentPackageTestCase returns a PackageTestCase that checks that the package name is not installed on the target
+// instances.
+func NewAbsentPackageTestCase(name string) PackageTestCase {
+	pktc := NewPackageTestCase(name)
+	pktc.absent = true
+	return pktc
+}
@@ -0,0 +1,102 @@
+package tester
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseVersionConstraints(t *testing.T) {
+	cases := []struct {
+		constraints string
+		expected    []versionConstraint
+	}{
+		{"1.2.3", []versionConstraint{{"=", "1.2.3"}}},
+		{">= 1.0.2k-24, < 1.1", []versionConstraint{{">=", "1.0.2k-24"}, {"<", "1.1"}}},
+		{">2,<=3,!=2.5,=2.7", []versionConstraint{{">", "2"}, {"<=", "3"}, {"!=", "2.5"}, {"=", "2.7"}}},
+		{"", nil},
+	}
+	for _, c := range cases {
+		t.Run("Ensure constraints are parsed for "+c.constraints, func(t *testing.T) {
+			a, err := parseVersionConstraints(c.constraints)
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			if !reflect.DeepEqual(c.expected, a) {
+				t.Errorf("Expected %v, but got %v", c.expected, a)
+			}
+		})
+	}
+}
+
+func TestParseVersionConstraintsErrors(t *testing.T) {
+	for _, constraints := range []string{"~> 1.2", "==1.2", "^1.2", ">= 1.0, =>2", ">="} {
+		t.Run("Ensure an error is returned for "+constraints, func(t *testing.T) {
+			if _, err := parseVersionConstraints(constraints); err == nil {
+				t.Errorf("Expected an error, but got nil")
+			}
+			testCase := NewPackageTestCase("openssl").WithVersionConstraint(constraints)
+			if testCase.Validate() == nil {
+				t.Errorf("Expected the test case to be invalid")
+			}
+			if e, a := "exit 1", testCase.buildCommandParameters()["commands"][0]; !strings.HasSuffix(a, e) {
+				t.Errorf("Expected the command to fail, but got %s", a)
+			}
+		})
+	}
+}
+
+func TestPackageTestCase(t *testing.T) {
+	cases := []struct {
+		caseName           string
+		packageTestCase    PackageTestCase
+		expectedAssertions []string
+	}{
+		{
+			caseName:        "Should check the installed version against each constraint",
+			packageTestCase: NewPackageTestCase("openssl").WithVersionConstraint(">= 1.0.2k-24, < 1.1"),
+			expectedAssertions: []string{
+				`if [ -z "$version" ]; then echo "FAIL: package "'openssl'" is not installed"; exit 1; fi`,
+				`    if dpkg --compare-versions "$1" lt "$2"; then echo -1; elif dpkg --compare-versions "$1" eq "$2"; then echo 0; else echo 1; fi; return`,
+				`  ea=$(epoch "$1"); eb=$(epoch "$2"); a=$(upstream "$1"); b=$(upstream "$2")`,
+				`  if [ "$ea" -ne "$eb" ]; then if [ "$ea" -lt "$eb" ]; then echo -1; else echo 1; fi`,
+				`[ "$(vercmp "$version" '1.0.2k-24')" -ge 0 ] || fail 'version' "$version" 'does not satisfy' '>= 1.0.2k-24'`,
+				`[ "$(vercmp "$version" '1.1')" -lt 0 ] || fail 'version' "$version" 'does not satisfy' '< 1.1'`,
+			},
+		},
+		{
+			caseName:        "Should check the package is not installed",
+			packageTestCase: NewAbsentPackageTestCase("telnet"),
+			expectedAssertions: []string{
+				`[ -z "$version" ] || fail 'package' 'telnet' 'is installed with version' "$version"`,
+			},
+		},
+		{
+			caseName:        "Should check the installed program version on windows",
+			packageTestCase: NewPackageTestCase("Amazon SSM Agent").WithWindows().WithVersionConstraint(">=3.1"),
+			expectedAssertions: []string{
+				`$program = Get-ItemProperty $uninstallKeys -ErrorAction SilentlyContinue | Where-Object { $_.DisplayName -eq 'Amazon SSM Agent' } | Select-Object -First 1`,
+				`if (-not ((Compare-Version $version '3.1') -ge 0)) { Fail "version" $version "does not satisfy" '>= 3.1' }`,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			assertCommandsContain(t, c.packageTestCase.buildCommandParameters()["commands"], c.expectedAssertions...)
+		})
+	}
+}
+
+func TestPackageTestCaseVersions(t *testing.T) {
+	result := TestResult{
+		Invocations: []InvocationResult{
+			{InstanceId: "i-1", Status: types.CommandInvocationStatusSuccess, Output: "manager=rpm\nversion=1.0.2k-24.amzn2.0.4\n"},
+			{InstanceId: "i-2", Status: types.CommandInvocationStatusFailed, Output: "manager=rpm\nversion=\nFAIL: package 'openssl' is not installed\n"},
+		},
+	}
+	expected := map[string]string{"i-1": "1.0.2k-24.amzn2.0.4", "i-2": ""}
+	if a := NewPackageTestCase("openssl").Versions(result); !reflect.DeepEqual(expected, a) {
+		t.Errorf("Expected %v, but got %v", expected, a)
+	}
+}
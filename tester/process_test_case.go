@@ -0,0 +1,87 @@
+package tester
+
+import "fmt"
+
+// ProcessTestCase configuration for a test that checks the number of processes running on the target instances that
+// match a pattern, eg. for applications that are not managed as a service.
+// The number of matching processes on each instance is reported as count in the output, available with
+// InvocationResult.Values.
+type ProcessTestCase struct {
+	platformScript
+	pattern         string // regular expression the process name, or command line, should match
+	fullCommandLine bool   // match pattern against the full command line instead of the process name
+	minCount        int    // the minimum number of matching processes
+	maxCount        int    // the maximum number of matching processes, not checked if negative
+}
+
+func (ptc ProcessTestCase) buildCommandParameters() map[string][]string {
+	if ptc.windows {
+		return shellCommandParameters(ptc.buildPowerShellScript())
+	}
+	flags := ""
+	if ptc.fullCommandLine {
+		flags = "-f "
+	}
+	assertions := []string{
+		fmt.Sprintf(`count=$(pgrep %s-- %s | wc -l | tr -d ' ')`, flags, shellQuote(ptc.pattern)),
+		`echo "count=$count"`,
+		fmt.Sprintf(`[ "$count" -ge %d ] || fail 'found' "$count" 'processes matching' %s 'expected at least %d'`,
+			ptc.minCount, shellQuote(ptc.pattern), ptc.minCount),
+	}
+	if ptc.maxCount >= 0 {
+		assertions = append(assertions, fmt.Sprintf(`[ "$count" -le %d ] || fail 'found' "$count" 'processes matching' %s 'expected at most %d'`,
+			ptc.maxCount, shellQuote(ptc.pattern), ptc.maxCount))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+func (ptc ProcessTestCase) buildPowerShellScript() []string {
+	property := "Name"
+	if ptc.fullCommandLine {
+		property = "CommandLine"
+	}
+	assertions := []string{
+		fmt.Sprintf(`$count = @(Get-CimInstance -ClassName Win32_Process | Where-Object { $_.%s -match %s -and $_.ProcessId -ne $PID }).Count`,
+			property, powerShellQuote(ptc.pattern)),
+		`Write-Output "count=$count"`,
+		fmt.Sprintf(`if ($count -lt %d) { Fail "found" $count "processes matching" %s "expected at least %d" }`,
+			ptc.minCount, powerShellQuote(ptc.pattern), ptc.minCount),
+	}
+	if ptc.maxCount >= 0 {
+		assertions = append(assertions, fmt.Sprintf(`if ($count -gt %d) { Fail "found" $count "processes matching" %s "expected at most %d" }`,
+			ptc.maxCount, powerShellQuote(ptc.pattern), ptc.maxCount))
+	}
+	return powerShellAssertionScript(assertions...)
+}
+
+// WithFullCommandLine returns a copy of the ProcessTestCase that matches the pattern against the full command line of
+// the processes, instead of the process name.
+func (ptc ProcessTestCase) WithFullCommandLine() ProcessTestCase {
+	ptc.fullCommandLine = true
+	return ptc
+}
+
+// WithCountBetween returns a copy of the ProcessTestCase that checks the number of matching processes is between min and
+// max inclusive. max may be negative to not check an upper bound, and both may be 0 to check no process is running.
+func (ptc ProcessTestCase) WithCountBetween(min int, max int) ProcessTestCase {
+	ptc.minCount = min
+	ptc.maxCount = max
+	return ptc
+}
+
+// WithWindows returns a copy of the ProcessTestCase that checks the processes on windows instances via PowerShell.
+func (ptc ProcessTestCase) WithWindows() ProcessTestCase {
+	ptc.windows = true
+	return ptc
+}
+
+// NewProcessTestCase is a constructor for ProcessTestCase type.
+// pattern should be a regular expression the process name should match, eg. "^java$".
+// By default the test checks that at least one matching process is running.
+func NewProcessTestCase(pattern string) ProcessTestCase {
+	return ProcessTestCase{
+		pattern:  pattern,
+		minCount: 1,
+		maxCount: -1,
+	}
+}
@@ -0,0 +1,66 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProcessTestCase(t *testing.T) {
+	cases := []struct {
+		caseName                  string
+		processTestCase           ProcessTestCase
+		expectedCommandParameters map[string][]string
+	}{
+		{
+			caseName:        "Should check at least one process matches by name by default",
+			processTestCase: NewProcessTestCase("^java$"),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`count=$(pgrep -- '^java$' | wc -l | tr -d ' ')`,
+					`echo "count=$count"`,
+					`[ "$count" -ge 1 ] || fail 'found' "$count" 'processes matching' '^java$' 'expected at least 1'`,
+					"exit $rc",
+				},
+			},
+		},
+		{
+			caseName:        "Should check the count range of processes matching the command line",
+			processTestCase: NewProcessTestCase("app.jar").WithFullCommandLine().WithCountBetween(2, 4),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`count=$(pgrep -f -- 'app.jar' | wc -l | tr -d ' ')`,
+					`echo "count=$count"`,
+					`[ "$count" -ge 2 ] || fail 'found' "$count" 'processes matching' 'app.jar' 'expected at least 2'`,
+					`[ "$count" -le 4 ] || fail 'found' "$count" 'processes matching' 'app.jar' 'expected at most 4'`,
+					"exit $rc",
+				},
+			},
+		},
+		{
+			caseName:        "Should check processes on windows instances",
+			processTestCase: NewProcessTestCase("^amazon-ssm-agent").WithWindows().WithCountBetween(1, 1),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"$rc = 0",
+					`function Fail { Write-Output ("FAIL: " + ($args -join " ")); $script:rc = 1 }`,
+					`$count = @(Get-CimInstance -ClassName Win32_Process | Where-Object { $_.Name -match '^amazon-ssm-agent' -and $_.ProcessId -ne $PID }).Count`,
+					`Write-Output "count=$count"`,
+					`if ($count -lt 1) { Fail "found" $count "processes matching" '^amazon-ssm-agent' "expected at least 1" }`,
+					`if ($count -gt 1) { Fail "found" $count "processes matching" '^amazon-ssm-agent' "expected at most 1" }`,
+					"exit $rc",
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if e, a := c.expectedCommandParameters, c.processTestCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package tester
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TestResult contains the results of a test command sent to the target instances.
+type TestResult struct {
+	CommandId   string             // the id of the command sent via the SendCommand API of SSM
+	Invocations []InvocationResult // the result of the command on each of the target instances
+}
+
+// Passed returns true if the test command was invoked on at least one instance and all the invocations succeeded.
+func (tr TestResult) Passed() bool {
+	if len(tr.Invocations) == 0 {
+		return false
+	}
+	for _, v := range tr.Invocations {
+		if v.Status != types.CommandInvocationStatusSuccess {
+			return false
+		}
+	}
+	return true
+}
+
+// InvocationResult contains the result of a test command on a single target instance.
+type InvocationResult struct {
+	InstanceId string                        // the id of the target instance
+	Status     types.CommandInvocationStatus // the status of the command invocation on the instance
+	Output     string                        // the output of the command, SSM truncates it to the first 2500 characters
+
+	StatusDetails string // the detailed status of the command invocation eg. DeliveryTimedOut
+	// the first non-zero response code of the command plugins, ie. the exit code of the first plugin to fail, or -1 while
+	// it runs. It is 0 if every plugin succeeded, or if the invocation was listed without its plugins.
+	ResponseCode      int32
+	RequestedDateTime time.Time // the time the command was requested on the instance
+}
+
+var valueLinePattern = regexp.MustCompile(`^([a-z/][a-zA-Z0-9_./-]*)=(.*)$`)
+
+// Values returns the name=value pairs reported by the test command in its output.
+// Test cases in this package report the actual state they observe on an instance as name=value lines, so that the state is
+// available for reporting even if the test passes.
+func (ir InvocationResult) Values() map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(ir.Output, "\n") {
+		if match := valueLinePattern.FindStringSubmatch(strings.TrimRight(line, "\r")); match != nil {
+			values[match[1]] = match[2]
+		}
+	}
+	return values
+}
+
+// Failures returns the reasons for the failed assertions reported by the test command in its output.
+func (ir InvocationResult) Failures() []string {
+	const prefix = "FAIL: "
+	var failures []string
+	for _, line := range strings.Split(ir.Output, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			failures = append(failures, strings.TrimRight(strings.TrimPrefix(line, prefix), "\r"))
+		}
+	}
+	return failures
+}
+
+func newInvocationResult(invocation types.CommandInvocation) InvocationResult {
+	result := InvocationResult{Status: invocation.Status}
+	if invocation.InstanceId != nil {
+		result.InstanceId = *invocation.InstanceId
+	}
+	if invocation.StatusDetails != nil {
+		result.StatusDetails = *invocation.StatusDetails
+	}
+	if invocation.RequestedDateTime != nil {
+		result.RequestedDateTime = *invocation.RequestedDateTime
+	}
+	var output []string
+	for _, plugin := range invocation.CommandPlugins {
+		if plugin.Output != nil {
+			output = append(output, *plugin.Output)
+		}
+		// the first plugin to fail decides the exit code of the command
+		if result.ResponseCode == 0 {
+			result.ResponseCode = plugin.ResponseCode
+		}
+	}
+	result.Output = strings.Join(output, "\n")
+	return result
+}
@@ -0,0 +1,97 @@
+package tester
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInvocationResultValuesAndFailures(t *testing.T) {
+	invocation := newInvocationResult(types.CommandInvocation{
+		InstanceId: stringPointer("i-1"),
+		Status:     types.CommandInvocationStatusFailed,
+		CommandPlugins: []types.CommandPlugin{
+			{Output: stringPointer("state=active\nenabled=disabled\nFAIL: unit is disabled\nnot a value\n")},
+		},
+	})
+	if e, a := map[string]string{"state": "active", "enabled": "disabled"}, invocation.Values(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected values %v, but got %v", e, a)
+	}
+	if e, a := []string{"unit is disabled"}, invocation.Failures(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected failures %v, but got %v", e, a)
+	}
+}
+
+func TestInvocationResultValueNames(t *testing.T) {
+	cases := []struct {
+		caseName      string
+		line          string
+		expectedValue map[string]string
+	}{
+		{"plain name", "state=active", map[string]string{"state": "active"}},
+		{"dotted name", "net.ipv4.ip_forward=1", map[string]string{"net.ipv4.ip_forward": "1"}},
+		{"name with slashes", "tcp/10.0.0.1/22=open", map[string]string{"tcp/10.0.0.1/22": "open"}},
+		{"absolute path", "/proc/sys/fs/file-max=65536", map[string]string{"/proc/sys/fs/file-max": "65536"}},
+		{"name with dashes and capitals", "tcp/ip-10-0-0-1/sshd-Main=open", map[string]string{"tcp/ip-10-0-0-1/sshd-Main": "open"}},
+		{"name starting with a capital", "State=active", map[string]string{}},
+		{"name starting with a dash", "-x=1", map[string]string{}},
+		{"failure", "FAIL: state=inactive", map[string]string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			invocation := InvocationResult{Output: c.line + "\n"}
+			if e, a := c.expectedValue, invocation.Values(); !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected values %v, but got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestNewInvocationResult(t *testing.T) {
+	requested := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	invocation := newInvocationResult(types.CommandInvocation{
+		InstanceId:        stringPointer("i-1"),
+		Status:            types.CommandInvocationStatusFailed,
+		StatusDetails:     stringPointer("Failed"),
+		RequestedDateTime: &requested,
+		CommandPlugins: []types.CommandPlugin{
+			{Output: stringPointer("FAIL: first"), ResponseCode: 2},
+			{Output: stringPointer("FAIL: second"), ResponseCode: 1},
+		},
+	})
+	expected := InvocationResult{
+		InstanceId:        "i-1",
+		Status:            types.CommandInvocationStatusFailed,
+		Output:            "FAIL: first\nFAIL: second",
+		StatusDetails:     "Failed",
+		ResponseCode:      2,
+		RequestedDateTime: requested,
+	}
+	if e, a := expected, invocation; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected %v, but got %v", e, a)
+	}
+}
+
+func TestTestResultPassed(t *testing.T) {
+	var cases = []struct {
+		caseName string
+		result   TestResult
+		expected bool
+	}{
+		{"Should not pass without invocations", TestResult{}, false},
+		{"Should pass if all invocations succeeded", TestResult{Invocations: []InvocationResult{
+			{Status: types.CommandInvocationStatusSuccess}, {Status: types.CommandInvocationStatusSuccess},
+		}}, true},
+		{"Should not pass if any invocation failed", TestResult{Invocations: []InvocationResult{
+			{Status: types.CommandInvocationStatusSuccess}, {Status: types.CommandInvocationStatusFailed},
+		}}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if a := c.result.Passed(); a != c.expected {
+				t.Errorf("Expected %v, but got %v", c.expected, a)
+			}
+		})
+	}
+}
@@ -2,16 +2,16 @@ package tester
 
 import (
 	"fmt"
-	"log"
-	"testing"
 	"time"
 )
 
 // retry is based on terratest RetryWithInterface from: https://github.com/gruntwork-io/terratest/blob/master/modules/retry/retry.go.
 // The original copyright and licence are as here: https://github.com/gruntwork-io/terratest/blob/master/LICENSE.
 // The a copy of the original NOTICE and LICENCE are available at the end of this file
-// retry is modified to that the MaxRetriesExceededError wraps and propagate the last underlying.
-func retry(t *testing.T, actionDescription string, maxRetries int, waitBetweenRetries time.Duration, action func() (interface{}, error)) (interface{}, error) {
+// retry is modified to that the MaxRetriesExceededError wraps and propagate the last underlying, and to report to a Logger
+// with args, and with the args of errors that have them.
+func retry(logger Logger, actionDescription string, maxRetries int, waitBetweenRetries time.Duration, action func() (interface{}, error),
+	args ...interface{}) (interface{}, error) {
 	var output interface{}
 	var err error
 
@@ -21,16 +21,21 @@ func retry(t *testing.T, actionDescription string, maxRetries int, waitBetweenRe
 		if err == nil {
 			return output, nil
 		}
+		attemptArgs := append(append([]interface{}{}, args...), "attempt", i+1)
+		if withArgs, ok := err.(interface{ logArgs() []interface{} }); ok {
+			attemptArgs = append(attemptArgs, withArgs.logArgs()...)
+		}
 
 		if _, isFatalErr := err.(fatalError); isFatalErr {
-			log.Printf("Returning due to fatal error: %v", err)
+			logger.Info(actionDescription+" returning due to fatal error", append(attemptArgs, "error", err)...)
 			return output, err
 		}
 
-		log.Printf("%s returned an error: %s. Sleeping for %s and will try again.", actionDescription, err.Error(), waitBetweenRetries)
+		logger.Debug(actionDescription+" will try again", append(attemptArgs, "error", err, "wait", waitBetweenRetries)...)
 		time.Sleep(waitBetweenRetries)
 	}
 
+	logger.Warn(actionDescription+" max retries exceeded", append(append([]interface{}{}, args...), "error", err)...)
 	return output, maxRetriesExceededError{underlying: err}
 }
 
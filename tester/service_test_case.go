@@ -0,0 +1,146 @@
+package tester
+
+import "fmt"
+
+// ServiceTestCase configuration for a test that checks the state of a systemd unit, or a windows service, on the target
+// instances, eg. that the ssm agent, CloudWatch agent and the application are up after an AMI bake.
+// The test fails on an instance if the service is not installed, or not in the expected state. The actual state of the
+// service on each instance is available from the TestResult with States.
+type ServiceTestCase struct {
+	platformScript
+	name    string // the name of the systemd unit or windows service
+	enabled *bool  // whether the service should be enabled to start on boot, not checked if nil
+	active  *bool  // whether the service should be active, ie. running, not checked if nil
+	user    string // the user the main process of the service should run as, not checked if empty
+}
+
+// ServiceState is the state of a service on a target instance, as reported by a ServiceTestCase.
+type ServiceState struct {
+	Installed bool   // whether the service is installed
+	Enabled   string // the enablement state eg. enabled, disabled, static, masked for systemd units or auto, manual, disabled for windows services
+	Active    string // the active state eg. active, inactive, failed for systemd units or running, stopped for windows services
+	User      string // the user the main process of the service is running as, empty if not running
+}
+
+func (stc ServiceTestCase) buildCommandParameters() map[string][]string {
+	if stc.windows {
+		return shellCommandParameters(stc.buildPowerShellScript())
+	}
+	name := shellQuote(stc.name)
+	assertions := []string{
+		fmt.Sprintf(`if [ "$(systemctl show -p LoadState %s | cut -d = -f 2)" != loaded ]; then echo "installed=no"; echo "FAIL: unit "%s" is not installed"; exit 1; fi`,
+			name, name),
+		fmt.Sprintf(`enabled=$(systemctl is-enabled %s 2>/dev/null); active=$(systemctl is-active %s 2>/dev/null)`, name, name),
+		fmt.Sprintf(`pid=$(systemctl show -p MainPID %s | cut -d = -f 2); user=""; [ "$pid" -gt 0 ] 2>/dev/null && user=$(ps -o user= -p "$pid")`, name),
+		`echo "installed=yes"; echo "enabled=$enabled"; echo "active=$active"; echo "user=$user"`,
+	}
+	switch {
+	case stc.enabled == nil:
+	case *stc.enabled:
+		assertions = append(assertions, `[ "$enabled" = enabled ] || fail 'unit is' "$enabled" 'expected enabled'`)
+	default:
+		assertions = append(assertions, `[ "$enabled" != enabled ] || fail 'unit is enabled, expected not enabled'`)
+	}
+	switch {
+	case stc.active == nil:
+	case *stc.active:
+		assertions = append(assertions, `[ "$active" = active ] || fail 'unit is' "$active" 'expected active'`)
+	default:
+		assertions = append(assertions, `[ "$active" != active ] || fail 'unit is active, expected not active'`)
+	}
+	if stc.user != "" {
+		assertions = append(assertions, expectEqual("user", "$user", stc.user))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+func (stc ServiceTestCase) buildPowerShellScript() []string {
+	name := powerShellQuote(stc.name)
+	assertions := []string{
+		fmt.Sprintf(`$svc = Get-CimInstance -ClassName Win32_Service | Where-Object { $_.Name -eq %s }`, name),
+		fmt.Sprintf(`if (-not $svc) { Write-Output "installed=no"; Write-Output ("FAIL: service " + %s + " is not installed"); exit 1 }`, name),
+		`$enabled = "$($svc.StartMode)".ToLower(); $active = "$($svc.State)".ToLower(); $user = ""`,
+		`if ($svc.ProcessId -gt 0) { $user = "$($svc.StartName)" }`,
+		`Write-Output "installed=yes"; Write-Output "enabled=$enabled"; Write-Output "active=$active"; Write-Output "user=$user"`,
+	}
+	switch {
+	case stc.enabled == nil:
+	case *stc.enabled:
+		assertions = append(assertions, `if ($enabled -ne "auto") { Fail "service start mode is" $enabled "expected auto" }`)
+	default:
+		assertions = append(assertions, `if ($enabled -eq "auto") { Fail "service start mode is auto, expected not auto" }`)
+	}
+	switch {
+	case stc.active == nil:
+	case *stc.active:
+		assertions = append(assertions, `if ($active -ne "running") { Fail "service is" $active "expected running" }`)
+	default:
+		assertions = append(assertions, `if ($active -eq "running") { Fail "service is running, expected not running" }`)
+	}
+	if stc.user != "" {
+		assertions = append(assertions, fmt.Sprintf(`if ($user -ne %s) { Fail "user is" $user "expected" %s }`,
+			powerShellQuote(stc.user), powerShellQuote(stc.user)))
+	}
+	return powerShellAssertionScript(assertions...)
+}
+
+// WithEnabled returns a copy of the ServiceTestCase that checks whether the service is enabled to start on boot.
+// For windows services, enabled means the start mode is automatic.
+func (stc ServiceTestCase) WithEnabled(enabled bool) ServiceTestCase {
+	stc.enabled = &enabled
+	return stc
+}
+
+// WithoutEnabledCheck returns a copy of the ServiceTestCase that does not check whether the service is enabled to start
+// on boot, eg. for a service that must be running but is started by another unit.
+func (stc ServiceTestCase) WithoutEnabledCheck() ServiceTestCase {
+	stc.enabled = nil
+	return stc
+}
+
+// WithActive returns a copy of the ServiceTestCase that checks whether the service is active, ie. running.
+func (stc ServiceTestCase) WithActive(active bool) ServiceTestCase {
+	stc.active = &active
+	return stc
+}
+
+// WithoutActiveCheck returns a copy of the ServiceTestCase that does not check whether the service is active.
+func (stc ServiceTestCase) WithoutActiveCheck() ServiceTestCase {
+	stc.active = nil
+	return stc
+}
+
+// WithUser returns a copy of the ServiceTestCase that checks the main process of the service is running as user.
+// For windows services user is the account the service logs on as, eg. LocalSystem.
+func (stc ServiceTestCase) WithUser(user string) ServiceTestCase {
+	stc.user = user
+	return stc
+}
+
+// WithWindows returns a copy of the ServiceTestCase that checks a windows service via PowerShell, instead of a systemd unit.
+func (stc ServiceTestCase) WithWindows() ServiceTestCase {
+	stc.windows = true
+	return stc
+}
+
+// States returns the state of the service on each instance in result, keyed by instance id.
+func (stc ServiceTestCase) States(result TestResult) map[string]ServiceState {
+	states := map[string]ServiceState{}
+	for _, v := range result.Invocations {
+		values := v.Values()
+		states[v.InstanceId] = ServiceState{
+			Installed: values["installed"] == "yes",
+			Enabled:   values["enabled"],
+			Active:    values["active"],
+			User:      values["user"],
+		}
+	}
+	return states
+}
+
+// NewServiceTestCase is a constructor for ServiceTestCase type.
+// name should be the name of the systemd unit eg. "amazon-ssm-agent" or "amazon-cloudwatch-agent.service".
+// By default the test checks that the unit is installed, enabled and active.
+func NewServiceTestCase(name string) ServiceTestCase {
+	return ServiceTestCase{name: name}.WithEnabled(true).WithActive(true)
+}
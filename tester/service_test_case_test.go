@@ -0,0 +1,98 @@
+package tester
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"reflect"
+	"testing"
+)
+
+func TestServiceTestCase(t *testing.T) {
+	cases := []struct {
+		caseName                  string
+		serviceTestCase           ServiceTestCase
+		expectedDocumentName      string
+		expectedCommandParameters map[string][]string
+	}{
+		{
+			caseName:             "Should check a systemd unit is installed, enabled and active by default",
+			serviceTestCase:      NewServiceTestCase("amazon-ssm-agent"),
+			expectedDocumentName: "AWS-RunShellScript",
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`if [ "$(systemctl show -p LoadState 'amazon-ssm-agent' | cut -d = -f 2)" != loaded ]; then echo "installed=no"; echo "FAIL: unit "'amazon-ssm-agent'" is not installed"; exit 1; fi`,
+					`enabled=$(systemctl is-enabled 'amazon-ssm-agent' 2>/dev/null); active=$(systemctl is-active 'amazon-ssm-agent' 2>/dev/null)`,
+					`pid=$(systemctl show -p MainPID 'amazon-ssm-agent' | cut -d = -f 2); user=""; [ "$pid" -gt 0 ] 2>/dev/null && user=$(ps -o user= -p "$pid")`,
+					`echo "installed=yes"; echo "enabled=$enabled"; echo "active=$active"; echo "user=$user"`,
+					`[ "$enabled" = enabled ] || fail 'unit is' "$enabled" 'expected enabled'`,
+					`[ "$active" = active ] || fail 'unit is' "$active" 'expected active'`,
+					"exit $rc",
+				},
+			},
+		},
+		{
+			caseName:             "Should only check a systemd unit is active without the enabled check",
+			serviceTestCase:      NewServiceTestCase("app").WithoutEnabledCheck(),
+			expectedDocumentName: "AWS-RunShellScript",
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`if [ "$(systemctl show -p LoadState 'app' | cut -d = -f 2)" != loaded ]; then echo "installed=no"; echo "FAIL: unit "'app'" is not installed"; exit 1; fi`,
+					`enabled=$(systemctl is-enabled 'app' 2>/dev/null); active=$(systemctl is-active 'app' 2>/dev/null)`,
+					`pid=$(systemctl show -p MainPID 'app' | cut -d = -f 2); user=""; [ "$pid" -gt 0 ] 2>/dev/null && user=$(ps -o user= -p "$pid")`,
+					`echo "installed=yes"; echo "enabled=$enabled"; echo "active=$active"; echo "user=$user"`,
+					`[ "$active" = active ] || fail 'unit is' "$active" 'expected active'`,
+					"exit $rc",
+				},
+			},
+		},
+		{
+			caseName:             "Should check a windows service is disabled, stopped and its user",
+			serviceTestCase:      NewServiceTestCase("Spooler").WithWindows().WithEnabled(false).WithActive(false).WithUser("LocalSystem"),
+			expectedDocumentName: "AWS-RunPowerShellScript",
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"$rc = 0",
+					`function Fail { Write-Output ("FAIL: " + ($args -join " ")); $script:rc = 1 }`,
+					`$svc = Get-CimInstance -ClassName Win32_Service | Where-Object { $_.Name -eq 'Spooler' }`,
+					`if (-not $svc) { Write-Output "installed=no"; Write-Output ("FAIL: service " + 'Spooler' + " is not installed"); exit 1 }`,
+					`$enabled = "$($svc.StartMode)".ToLower(); $active = "$($svc.State)".ToLower(); $user = ""`,
+					`if ($svc.ProcessId -gt 0) { $user = "$($svc.StartName)" }`,
+					`Write-Output "installed=yes"; Write-Output "enabled=$enabled"; Write-Output "active=$active"; Write-Output "user=$user"`,
+					`if ($enabled -eq "auto") { Fail "service start mode is auto, expected not auto" }`,
+					`if ($active -eq "running") { Fail "service is running, expected not running" }`,
+					`if ($user -ne 'LocalSystem') { Fail "user is" $user "expected" 'LocalSystem' }`,
+					"exit $rc",
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if e, a := c.expectedDocumentName, c.serviceTestCase.documentName(); e != a {
+				t.Errorf("Expected DocumentName to be %s, got %s", e, a)
+			}
+			if e, a := c.expectedCommandParameters, c.serviceTestCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+			}
+		})
+	}
+}
+
+func TestServiceTestCaseStates(t *testing.T) {
+	result := TestResult{
+		Invocations: []InvocationResult{
+			{InstanceId: "i-1", Status: types.CommandInvocationStatusSuccess, Output: "installed=yes\nenabled=enabled\nactive=active\nuser=root\n"},
+			{InstanceId: "i-2", Status: types.CommandInvocationStatusFailed, Output: "installed=no\nFAIL: unit 'app' is not installed\n"},
+		},
+	}
+	expected := map[string]ServiceState{
+		"i-1": {Installed: true, Enabled: "enabled", Active: "active", User: "root"},
+		"i-2": {},
+	}
+	if a := NewServiceTestCase("app").States(result); !reflect.DeepEqual(expected, a) {
+		t.Errorf("Expected %v, but got %v", expected, a)
+	}
+}
@@ -0,0 +1,81 @@
+package tester
+
+import "strings"
+
+// runShellScript provides the ssm document for test cases that are sent to linux target instances as shell scripts.
+type runShellScript struct{}
+
+func (rss runShellScript) documentName() string {
+	return "AWS-RunShellScript"
+}
+
+func (rss runShellScript) documentVersion() string {
+	return "$LATEST"
+}
+
+// assertionScript builds the command lines of a test case that is made up of a number of assertions.
+// Each assertion should call fail with a reason when it does not hold. fail prints the reason prefixed with FAIL: and
+// marks the script as failed without stopping it, so that every mismatch on an instance is reported and not just the first.
+func assertionScript(assertions ...string) []string {
+	lines := []string{
+		"rc=0",
+		`fail() { echo "FAIL: $*"; rc=1; }`,
+	}
+	lines = append(lines, assertions...)
+	return append(lines, "exit $rc")
+}
+
+func shellCommandParameters(lines []string) map[string][]string {
+	return map[string][]string{"commands": lines}
+}
+
+// shellQuote quotes s so that it is passed to a shell command as a single literal argument.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// runPowerShellScript provides the ssm document for test cases that are sent to windows target instances as PowerShell scripts.
+type runPowerShellScript struct{}
+
+func (rpss runPowerShellScript) documentName() string {
+	return "AWS-RunPowerShellScript"
+}
+
+func (rpss runPowerShellScript) documentVersion() string {
+	return "$LATEST"
+}
+
+// powerShellAssertionScript is like assertionScript but builds the command lines for a PowerShell script.
+// Each assertion should call Fail with a reason when it does not hold.
+func powerShellAssertionScript(assertions ...string) []string {
+	lines := []string{
+		"$rc = 0",
+		`function Fail { Write-Output ("FAIL: " + ($args -join " ")); $script:rc = 1 }`,
+	}
+	lines = append(lines, assertions...)
+	return append(lines, "exit $rc")
+}
+
+// powerShellQuote quotes s so that it is passed to a PowerShell command as a single literal string.
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// platformScript provides the ssm document for test cases that support both linux and windows target instances.
+type platformScript struct {
+	windows bool // whether the test case is sent to windows target instances
+}
+
+func (ps platformScript) documentName() string {
+	if ps.windows {
+		return runPowerShellScript{}.documentName()
+	}
+	return runShellScript{}.documentName()
+}
+
+func (ps platformScript) documentVersion() string {
+	if ps.windows {
+		return runPowerShellScript{}.documentVersion()
+	}
+	return runShellScript{}.documentVersion()
+}
@@ -0,0 +1,44 @@
+package tester
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", `'plain'`},
+		{"it's", `'it'"'"'s'`},
+		{"$(reboot)", `'$(reboot)'`},
+	}
+	for _, c := range cases {
+		t.Run("Ensure shellQuote quotes "+c.input, func(t *testing.T) {
+			if e, a := c.expected, shellQuote(c.input); e != a {
+				t.Errorf("Expected %s, but got %s", e, a)
+			}
+		})
+	}
+}
+
+func TestPowerShellQuote(t *testing.T) {
+	if e, a := `'it''s $env:PATH'`, powerShellQuote("it's $env:PATH"); e != a {
+		t.Errorf("Expected %s, but got %s", e, a)
+	}
+}
+
+// assertCommandsContain is a test helper that checks each of expected is one of the command lines in commands.
+func assertCommandsContain(t *testing.T, commands []string, expected ...string) {
+	t.Helper()
+	for _, e := range expected {
+		found := false
+		for _, a := range commands {
+			found = found || a == e
+		}
+		if !found {
+			t.Errorf("Expected the commands to contain \n%s, but got \n%s", e, strings.Join(commands, "\n"))
+		}
+	}
+}
@@ -24,9 +24,7 @@ func (stc ShellTestCase) buildCommandString() string {
 	if !stc.condition {
 		exitCodeForFailure, exitCodeForSuccess = exitCodeForSuccess, exitCodeForFailure
 	}
-	result := fmt.Sprintf(stringTemplatePrefix, stc.command, exitCodeForSuccess, exitCodeForFailure)
-	fmt.Println(result)
-	return result
+	return fmt.Sprintf(stringTemplatePrefix, stc.command, exitCodeForSuccess, exitCodeForFailure)
 }
 
 func (stc ShellTestCase) buildCommandParameters() map[string][]string {
@@ -0,0 +1,569 @@
+package tester
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Suite is a suite of tests declared as data, so that behaviour checks can be added without writing Go code.
+// A Suite is usually loaded from a YAML or JSON file with LoadSuite, and run with RunSuite, eg.
+//
+//	retry:
+//	  maxRetries: 10
+//	  waitBetweenRetries: 5s
+//	targets:
+//	  app:
+//	    tag:
+//	      Name: app-instance
+//	  workers:
+//	    autoScalingGroup: workers-asg
+//	tests:
+//	  - name: app can connect to the database
+//	    target: app
+//	    tcp:
+//	      endpoint: mydb.privatedns
+//	      port: 3306
+//	  - name: app cannot connect to the internet
+//	    target: app
+//	    tcp:
+//	      endpoint: www.example.com
+//	      port: 443
+//	    expect: failure
+//	  - name: workers run the agent
+//	    target: workers
+//	    service:
+//	      name: amazon-cloudwatch-agent
+//
+// Each test has a name, the name of one of the targets, and exactly one test case.
+type Suite struct {
+	Retry   *SuiteRetry            `yaml:"retry,omitempty"` // the retry settings of all the tests, NewRetryDefaultConfig if not set
+	Targets map[string]SuiteTarget `yaml:"targets"`         // the instances to target, by name
+	Tests   []SuiteTest            `yaml:"tests"`           // the tests, in the order they run
+
+	logger Logger // the logger of all the tests, t.Logf if not set
+	noWait bool   // whether to poll for the results of the tests without waiting between retries
+}
+
+// WithLogger returns a copy of the Suite that reports the progress of its tests to logger, as in RetryConfig.WithLogger.
+func (s Suite) WithLogger(logger Logger) Suite {
+	s.logger = logger
+	return s
+}
+
+// WithoutWait returns a copy of the Suite that polls for the results of its tests without waiting between retries,
+// while keeping their maxRetries, eg. to replay a recorded run with testerreplay.
+func (s Suite) WithoutWait() Suite {
+	s.noWait = true
+	return s
+}
+
+// SuiteRetry are the settings for polling for the results of a test, as in NewRetryConfig.
+type SuiteRetry struct {
+	MaxRetries         int           `yaml:"maxRetries"`
+	WaitBetweenRetries time.Duration `yaml:"waitBetweenRetries"` // a duration eg. 5s
+}
+
+// SuiteTarget selects the instances to run a test on. If more than one selector is set, instances must match all of them.
+type SuiteTarget struct {
+	Tag              map[string]string `yaml:"tag,omitempty"`              // tag keys and the values the instances should have
+	InstanceIds      []string          `yaml:"instanceIds,omitempty"`      // the ids of the instances
+	AutoScalingGroup string            `yaml:"autoScalingGroup,omitempty"` // the name of the auto scaling group of the instances
+}
+
+// SuiteTest is a test of a Suite. Exactly one of the test cases should be set.
+type SuiteTest struct {
+	Name   string      `yaml:"name"`
+	Target string      `yaml:"target"`           // the name of one of the targets of the Suite
+	Expect string      `yaml:"expect,omitempty"` // success, the default, or failure for negative tests
+	Retry  *SuiteRetry `yaml:"retry,omitempty"`  // overrides the retry settings of the Suite
+
+	Shell   *SuiteShellTest   `yaml:"shell,omitempty"`
+	Tcp     *SuiteTcpTest     `yaml:"tcp,omitempty"`
+	Http    *SuiteHttpTest    `yaml:"http,omitempty"`
+	Tls     *SuiteTlsTest     `yaml:"tls,omitempty"`
+	Service *SuiteServiceTest `yaml:"service,omitempty"`
+	Process *SuiteProcessTest `yaml:"process,omitempty"`
+	Package *SuitePackageTest `yaml:"package,omitempty"`
+	File    *SuiteFileTest    `yaml:"file,omitempty"`
+}
+
+// SuiteShellTest runs a shell command, as in NewShellTestCase.
+type SuiteShellTest struct {
+	Command string `yaml:"command"`
+}
+
+// SuiteTcpTest checks a TCP connection can be made, as in TcpConnectionTestWithTagName.
+type SuiteTcpTest struct {
+	Endpoint         string `yaml:"endpoint"`
+	Port             int    `yaml:"port"`
+	TimeoutInSeconds int    `yaml:"timeoutInSeconds,omitempty"` // 3 if not set
+}
+
+// SuiteHttpTest makes an HTTP request, as in NewHttpTestCase.
+type SuiteHttpTest struct {
+	Url              string `yaml:"url"`
+	Status           int    `yaml:"status,omitempty"` // 200 if not set
+	BodyMatching     string `yaml:"bodyMatching,omitempty"`
+	TimeoutInSeconds int    `yaml:"timeoutInSeconds,omitempty"`
+}
+
+// SuiteTlsTest checks the TLS certificate of an endpoint, as in NewTlsTestCase.
+type SuiteTlsTest struct {
+	Endpoint           string `yaml:"endpoint"`
+	Port               int    `yaml:"port"`
+	ServerName         string `yaml:"serverName,omitempty"`
+	MinDaysUntilExpiry int    `yaml:"minDaysUntilExpiry,omitempty"`
+	ProtocolVersion    string `yaml:"protocolVersion,omitempty"`
+}
+
+// SuiteServiceTest checks the state of a service, as in NewServiceTestCase.
+type SuiteServiceTest struct {
+	Name          string `yaml:"name"`
+	Enabled       *bool  `yaml:"enabled,omitempty"`       // true if not set
+	Active        *bool  `yaml:"active,omitempty"`        // true if not set
+	IgnoreEnabled bool   `yaml:"ignoreEnabled,omitempty"` // do not check whether the service is enabled
+	IgnoreActive  bool   `yaml:"ignoreActive,omitempty"`  // do not check whether the service is active
+	User          string `yaml:"user,omitempty"`
+	Windows       bool   `yaml:"windows,omitempty"`
+}
+
+// SuiteProcessTest checks the number of running processes, as in NewProcessTestCase.
+type SuiteProcessTest struct {
+	Pattern         string `yaml:"pattern"`
+	FullCommandLine bool   `yaml:"fullCommandLine,omitempty"`
+	MinCount        *int   `yaml:"minCount,omitempty"` // 1 if not set
+	MaxCount        *int   `yaml:"maxCount,omitempty"` // not checked if not set
+	Windows         bool   `yaml:"windows,omitempty"`
+}
+
+// SuitePackageTest checks a package is installed, or absent, as in NewPackageTestCase.
+type SuitePackageTest struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version,omitempty"` // a version constraint eg. ">= 1.1.1k"
+	Absent  bool   `yaml:"absent,omitempty"`  // version should not be set
+	Windows bool   `yaml:"windows,omitempty"`
+}
+
+// SuiteFileTest checks a file, as in NewFileTestCase.
+type SuiteFileTest struct {
+	Path            string `yaml:"path"`
+	Absent          bool   `yaml:"absent,omitempty"` // the other checks should not be set
+	Type            string `yaml:"type,omitempty"`   // file, directory or symlink
+	Owner           string `yaml:"owner,omitempty"`
+	Group           string `yaml:"group,omitempty"`
+	Mode            string `yaml:"mode,omitempty"`
+	Sha256          string `yaml:"sha256,omitempty"`
+	ContentMatching string `yaml:"contentMatching,omitempty"`
+}
+
+const (
+	expectSuccess = "success"
+	expectFailure = "failure"
+)
+
+// LoadSuite reads and parses the YAML or JSON suite file at path, and validates it.
+func LoadSuite(path string) (Suite, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Suite{}, err
+	}
+	return ParseSuite(data)
+}
+
+// ParseSuite parses a YAML or JSON suite, and validates it. Unknown fields are an error, to catch typos.
+func ParseSuite(data []byte) (Suite, error) {
+	var suite Suite
+	if err := yaml.UnmarshalStrict(data, &suite); err != nil {
+		return Suite{}, err
+	}
+	return suite, suite.Validate()
+}
+
+// Validate returns an error describing the first problem found with the suite, or nil if every test can be run.
+func (s Suite) Validate() error {
+	if len(s.Tests) == 0 {
+		return fmt.Errorf("suite has no tests")
+	}
+	for name, target := range s.Targets {
+		if _, err := target.target(); err != nil {
+			return fmt.Errorf("target %s: %w", name, err)
+		}
+	}
+	names := map[string]bool{}
+	for i, test := range s.Tests {
+		if test.Name == "" {
+			return fmt.Errorf("test %d has no name", i+1)
+		}
+		if names[test.Name] {
+			return fmt.Errorf("test %s: name is not unique", test.Name)
+		}
+		names[test.Name] = true
+		if _, _, _, err := s.build(test); err != nil {
+			return fmt.Errorf("test %s: %w", test.Name, err)
+		}
+	}
+	return nil
+}
+
+// RunSuite runs each of the tests of the suite as a subtest named after the test, with RunTestCaseForTarget.
+// It fails the test immediately if the suite is not valid.
+func RunSuite(t *testing.T, client commandSenderLister, suite Suite) {
+	if err := suite.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range suite.Tests {
+		test := v
+		t.Run(test.Name, func(t *testing.T) {
+			testCase, target, retryConfig, _ := suite.build(test)
+			RunTestCaseForTarget(t, client, testCase, target, retryConfig)
+		})
+	}
+}
+
+// SuiteTestResult is the outcome of a test of a Suite.
+type SuiteTestResult struct {
+	Name     string        // the name of the test
+	Target   string        // the name of the target of the test
+	Passed   bool          // whether the test passed on all the target instances
+	Err      error         // the reason the test did not pass, nil if it passed
+	Result   TestResult    // the status and output of the test command on each of the target instances
+	Duration time.Duration // the time taken to send the test command and poll for its results
+
+	StartedAt    time.Time           // the time the test command was sent
+	DocumentName string              // the SSM document of the test command
+	Parameters   map[string][]string // the parameters of the test command
+	Targets      []types.Target      // the targets the test command was sent to
+}
+
+// SuiteResult are the results of the tests of a Suite, in the order they ran.
+type SuiteResult struct {
+	Tests []SuiteTestResult
+}
+
+// Passed returns true if every test of the suite passed.
+func (sr SuiteResult) Passed() bool {
+	for _, v := range sr.Tests {
+		if !v.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// RunSuiteE is like RunSuite but does not need a *testing.T, so that suites can be run outside of go test, eg. from a
+// command line tool. It returns the result of every test, and an error only if the suite is not valid.
+func RunSuiteE(client commandSenderLister, suite Suite) (SuiteResult, error) {
+	if err := suite.Validate(); err != nil {
+		return SuiteResult{}, err
+	}
+	var result SuiteResult
+	for _, test := range suite.Tests {
+		testCase, target, retryConfig, _ := suite.build(test)
+		input := newSendCommandInput(testCase, target)
+		start := time.Now()
+		testResult, err := RunTestCaseForTargetWithResultsE(nil, client, testCase, target, retryConfig)
+		passed := err == nil && testResult.Passed()
+		if err == nil && !passed {
+			err = fmt.Errorf("test command did not succeed on all of the target instances")
+		}
+		result.Tests = append(result.Tests, SuiteTestResult{
+			Name:         test.Name,
+			Target:       test.Target,
+			Passed:       passed,
+			Err:          err,
+			Result:       testResult,
+			Duration:     time.Since(start),
+			StartedAt:    start,
+			DocumentName: *input.DocumentName,
+			Parameters:   input.Parameters,
+			Targets:      input.Targets,
+		})
+	}
+	return result, nil
+}
+
+// Filter returns a copy of the suite with only the tests whose name matches the regular expression pattern.
+func (s Suite) Filter(pattern string) (Suite, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return s, err
+	}
+	var tests []SuiteTest
+	for _, v := range s.Tests {
+		if re.MatchString(v.Name) {
+			tests = append(tests, v)
+		}
+	}
+	s.Tests = tests
+	return s, nil
+}
+
+// DescribeSuiteTargetsE resolves each of the targets of the suite to its running instances, keyed by target name.
+func DescribeSuiteTargetsE(client instanceDescriber, suite Suite) (map[string][]TargetInstance, error) {
+	instances := map[string][]TargetInstance{}
+	for name, suiteTarget := range suite.Targets {
+		target, err := suiteTarget.target()
+		if err != nil {
+			return nil, fmt.Errorf("target %s: %w", name, err)
+		}
+		if instances[name], err = DescribeTargetInstancesE(client, target); err != nil {
+			return nil, fmt.Errorf("target %s: %w", name, err)
+		}
+	}
+	return instances, nil
+}
+
+// build returns the test case, target and retry settings to run test with.
+func (s Suite) build(test SuiteTest) (commandParameterBuilder, targetParamBuilder, RetryConfig, error) {
+	retryConfig := NewRetryDefaultConfig()
+	for _, v := range []*SuiteRetry{s.Retry, test.Retry} {
+		if v != nil {
+			retryConfig = NewRetryConfig(v.MaxRetries, v.WaitBetweenRetries)
+		}
+	}
+	if s.noWait {
+		retryConfig.waitBetweenRetries = 0
+	}
+	retryConfig = retryConfig.WithLogger(s.logger)
+	suiteTarget, ok := s.Targets[test.Target]
+	if !ok {
+		return nil, nil, retryConfig, fmt.Errorf("target %q is not defined", test.Target)
+	}
+	target, err := suiteTarget.target()
+	if err != nil {
+		return nil, nil, retryConfig, err
+	}
+	if test.Expect != "" && test.Expect != expectSuccess && test.Expect != expectFailure {
+		return nil, nil, retryConfig, fmt.Errorf("expect should be %s or %s, not %q", expectSuccess, expectFailure, test.Expect)
+	}
+	testCase, err := test.testCase()
+	return testCase, target, retryConfig, err
+}
+
+func (st SuiteTarget) target() (targetParamBuilder, error) {
+	var targets combinedTarget
+	var keys []string
+	for key := range st.Tag {
+		keys = append(keys, key)
+	}
+	// sorted so that the same command is sent every run
+	sort.Strings(keys)
+	for _, key := range keys {
+		targets = append(targets, NewTagTarget(key, st.Tag[key]))
+	}
+	if len(st.InstanceIds) > 0 {
+		targets = append(targets, NewInstanceIdsTarget(st.InstanceIds...))
+	}
+	if st.AutoScalingGroup != "" {
+		targets = append(targets, NewAutoScalingGroupTarget(st.AutoScalingGroup))
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("one of tag, instanceIds or autoScalingGroup should be set")
+	}
+	return targets, nil
+}
+
+// combinedTarget targets the instances that match all of its targets.
+type combinedTarget []targetParamBuilder
+
+func (ct combinedTarget) buildTargetParameters() []types.Target {
+	var targets []types.Target
+	for _, v := range ct {
+		targets = append(targets, v.buildTargetParameters()...)
+	}
+	return targets
+}
+
+func (st SuiteTest) testCase() (commandParameterBuilder, error) {
+	var testCases []commandParameterBuilder
+	windows := false
+	success := st.Expect != expectFailure
+	if v := st.Shell; v != nil {
+		if v.Command == "" {
+			return nil, missingFieldError{kind: "shell", field: "command"}
+		}
+		testCases = append(testCases, NewShellTestCase(v.Command, success))
+	}
+	if v := st.Tcp; v != nil {
+		if v.Endpoint == "" || v.Port == 0 {
+			return nil, missingFieldError{kind: "tcp", field: "endpoint and port"}
+		}
+		timeout := v.TimeoutInSeconds
+		if timeout == 0 {
+			timeout = 3
+		}
+		testCases = append(testCases, NewShellTestCase(tcpConnectionTestShellCommand(timeout, v.Endpoint, strconv.Itoa(v.Port)), success))
+	}
+	if v := st.Http; v != nil {
+		if v.Url == "" {
+			return nil, missingFieldError{kind: "http", field: "url"}
+		}
+		testCase := NewHttpTestCase(v.Url).WithBodyMatching(v.BodyMatching)
+		if v.Status != 0 {
+			testCase = testCase.WithStatus(v.Status)
+		}
+		if v.TimeoutInSeconds != 0 {
+			testCase = testCase.WithTimeout(v.TimeoutInSeconds)
+		}
+		testCases = append(testCases, testCase)
+	}
+	if v := st.Tls; v != nil {
+		if v.Endpoint == "" || v.Port == 0 {
+			return nil, missingFieldError{kind: "tls", field: "endpoint and port"}
+		}
+		testCase := NewTlsTestCase(v.Endpoint, strconv.Itoa(v.Port)).WithMinDaysUntilExpiry(v.MinDaysUntilExpiry).WithProtocolVersion(v.ProtocolVersion)
+		if v.ServerName != "" {
+			testCase = testCase.WithServerName(v.ServerName)
+		}
+		testCases = append(testCases, testCase)
+	}
+	if v := st.Service; v != nil {
+		if v.Name == "" {
+			return nil, missingFieldError{kind: "service", field: "name"}
+		}
+		testCase := NewServiceTestCase(v.Name).WithUser(v.User)
+		if v.Enabled != nil {
+			testCase = testCase.WithEnabled(*v.Enabled)
+		}
+		if v.Active != nil {
+			testCase = testCase.WithActive(*v.Active)
+		}
+		if v.IgnoreEnabled {
+			if v.Enabled != nil {
+				return nil, fmt.Errorf("service sets both enabled and ignoreEnabled")
+			}
+			testCase = testCase.WithoutEnabledCheck()
+		}
+		if v.IgnoreActive {
+			if v.Active != nil {
+				return nil, fmt.Errorf("service sets both active and ignoreActive")
+			}
+			testCase = testCase.WithoutActiveCheck()
+		}
+		if windows = v.Windows; windows {
+			testCase = testCase.WithWindows()
+		}
+		testCases = append(testCases, testCase)
+	}
+	if v := st.Process; v != nil {
+		if v.Pattern == "" {
+			return nil, missingFieldError{kind: "process", field: "pattern"}
+		}
+		testCase := NewProcessTestCase(v.Pattern)
+		if v.FullCommandLine {
+			testCase = testCase.WithFullCommandLine()
+		}
+		if v.MinCount != nil || v.MaxCount != nil {
+			min, max := 1, -1
+			if v.MinCount != nil {
+				min = *v.MinCount
+			}
+			if v.MaxCount != nil {
+				max = *v.MaxCount
+			}
+			testCase = testCase.WithCountBetween(min, max)
+		}
+		if windows = v.Windows; windows {
+			testCase = testCase.WithWindows()
+		}
+		testCases = append(testCases, testCase)
+	}
+	if v := st.Package; v != nil {
+		if v.Name == "" {
+			return nil, missingFieldError{kind: "package", field: "name"}
+		}
+		testCase := NewPackageTestCase(v.Name).WithVersionConstraint(v.Version)
+		if err := testCase.Validate(); err != nil {
+			return nil, err
+		}
+		if v.Absent {
+			if v.Version != "" {
+				return nil, absentConflictError{kind: "package", field: "version"}
+			}
+			testCase = NewAbsentPackageTestCase(v.Name)
+		}
+		if windows = v.Windows; windows {
+			testCase = testCase.WithWindows()
+		}
+		testCases = append(testCases, testCase)
+	}
+	if v := st.File; v != nil {
+		if v.Path == "" {
+			return nil, missingFieldError{kind: "file", field: "path"}
+		}
+		testCase := NewFileTestCase(v.Path).WithMode(v.Mode).WithSha256(v.Sha256).WithContentMatching(v.ContentMatching)
+		if v.Absent {
+			fields := []struct{ name, value string }{{"type", v.Type}, {"owner", v.Owner}, {"group", v.Group}, {"mode", v.Mode},
+				{"sha256", v.Sha256}, {"contentMatching", v.ContentMatching}}
+			for _, field := range fields {
+				if field.value != "" {
+					return nil, absentConflictError{kind: "file", field: field.name}
+				}
+			}
+			testCase = NewAbsentFileTestCase(v.Path)
+		}
+		if v.Type != "" {
+			testCase = testCase.WithType(FileType(v.Type))
+		}
+		if v.Owner != "" || v.Group != "" {
+			testCase = testCase.WithOwner(v.Owner, v.Group)
+		}
+		testCases = append(testCases, testCase)
+	}
+
+	if len(testCases) != 1 {
+		return nil, fmt.Errorf("exactly one test case should be set, found %d", len(testCases))
+	}
+	testCase := testCases[0]
+	if _, isShellTestCase := testCase.(ShellTestCase); isShellTestCase || success {
+		return testCase, nil
+	}
+	if windows {
+		return nil, fmt.Errorf("expect %s is not supported for windows test cases", expectFailure)
+	}
+	return invertedTestCase{testCase}, nil
+}
+
+type missingFieldError struct {
+	kind  string
+	field string
+}
+
+func (err missingFieldError) Error() string {
+	return fmt.Sprintf("%s test should have %s set", err.kind, err.field)
+}
+
+// absentConflictError is returned for a test of something absent that also sets checks of it, which cannot be checked.
+type absentConflictError struct {
+	kind  string
+	field string
+}
+
+func (err absentConflictError) Error() string {
+	return fmt.Sprintf("%s test with absent set should not have %s set", err.kind, err.field)
+}
+
+// invertedTestCase passes where its shell script test case fails, and fails where it passes.
+// The FAIL: lines of the shell script are expected, so they are reported as "expected failure: " lines, and are not
+// failures of the inverted test.
+type invertedTestCase struct {
+	commandParameterBuilder
+}
+
+func (itc invertedTestCase) buildCommandParameters() map[string][]string {
+	commands := append([]string{"output=$("}, itc.commandParameterBuilder.buildCommandParameters()["commands"]...)
+	return shellCommandParameters(append(commands,
+		")",
+		"status=$?",
+		`[ -z "$output" ] || echo "$output" | sed 's/^FAIL: /expected failure: /'`,
+		`if [ $status -eq 0 ]; then echo "FAIL: expected the test to fail"; exit 1; fi`,
+		"exit 0",
+	))
+}
@@ -0,0 +1,345 @@
+package tester
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testSuite = `
+retry:
+  maxRetries: 2
+  waitBetweenRetries: 5s
+targets:
+  app:
+    tag:
+      Name: app-instance
+  workers:
+    autoScalingGroup: workers-asg
+    instanceIds: [i-1, i-2]
+tests:
+  - name: app can connect to the database
+    target: app
+    tcp:
+      endpoint: mydb.privatedns
+      port: 3306
+  - name: app cannot connect to the internet
+    target: app
+    expect: failure
+    tcp:
+      endpoint: www.example.com
+      port: 443
+  - name: workers do not have telnet
+    target: workers
+    retry:
+      maxRetries: 0
+      waitBetweenRetries: 1s
+    package:
+      name: telnet
+      absent: true
+`
+
+func TestParseSuite(t *testing.T) {
+	suite, err := ParseSuite([]byte(testSuite))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if e, a := 3, len(suite.Tests); e != a {
+		t.Fatalf("Expected %d tests, but got %d", e, a)
+	}
+
+	cases := []struct {
+		caseName            string
+		test                SuiteTest
+		expectedTestCase    commandParameterBuilder
+		expectedTargets     []types.Target
+		expectedRetryConfig RetryConfig
+	}{
+		{
+			"tcp test with the suite retry settings",
+			suite.Tests[0],
+			NewShellTestCase("timeout 3 bash -c '</dev/tcp/mydb.privatedns/3306'", true),
+			[]types.Target{{Key: stringPointer("tag:Name"), Values: []string{"app-instance"}}},
+			NewRetryConfig(2, 5*time.Second),
+		},
+		{
+			"negative tcp test",
+			suite.Tests[1],
+			NewShellTestCase("timeout 3 bash -c '</dev/tcp/www.example.com/443'", false),
+			[]types.Target{{Key: stringPointer("tag:Name"), Values: []string{"app-instance"}}},
+			NewRetryConfig(2, 5*time.Second),
+		},
+		{
+			"package test with its own retry settings and combined targets",
+			suite.Tests[2],
+			NewAbsentPackageTestCase("telnet"),
+			[]types.Target{
+				{Key: stringPointer("InstanceIds"), Values: []string{"i-1", "i-2"}},
+				{Key: stringPointer("tag:aws:autoscaling:groupName"), Values: []string{"workers-asg"}},
+			},
+			NewRetryConfig(0, time.Second),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			testCase, target, retryConfig, err := suite.build(c.test)
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			if e, a := c.expectedTestCase, testCase; !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the test case to be %v, but got %v", e, a)
+			}
+			if e, a := c.expectedTargets, target.buildTargetParameters(); !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the targets to be %v, but got %v", e, a)
+			}
+			if e, a := c.expectedRetryConfig, retryConfig; !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the retry config to be %v, but got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestSuiteWithoutWait(t *testing.T) {
+	suite, err := ParseSuite([]byte(testSuite))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	suite = suite.WithoutWait()
+	var retryConfigs []RetryConfig
+	for _, v := range suite.Tests {
+		_, _, retryConfig, err := suite.build(v)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		retryConfigs = append(retryConfigs, retryConfig)
+	}
+	if e, a := []RetryConfig{NewRetryConfig(2, 0), NewRetryConfig(2, 0), NewRetryConfig(0, 0)}, retryConfigs; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the retry configs to be %v, but got %v", e, a)
+	}
+}
+
+func TestParseSuiteErrors(t *testing.T) {
+	cases := []struct {
+		caseName string
+		suite    string
+	}{
+		{"unknown field", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, shel: {command: 'true'}}]"},
+		{"no tests", "targets: {app: {tag: {Name: app}}}"},
+		{"undefined target", "tests: [{name: a, target: app, shell: {command: 'true'}}]"},
+		{"empty target", "targets: {app: {}}\ntests: [{name: a, target: app, shell: {command: 'true'}}]"},
+		{"duplicate name", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, shell: {command: 'true'}}, {name: a, target: app, shell: {command: 'true'}}]"},
+		{"no test case", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app}]"},
+		{"two test cases", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, shell: {command: 'true'}, http: {url: 'http://localhost'}}]"},
+		{"missing field", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, tcp: {endpoint: localhost}}]"},
+		{"invalid expect", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, expect: fail, shell: {command: 'true'}}]"},
+		{"windows failure", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, expect: failure, service: {name: w32time, windows: true}}]"},
+		{"invalid version constraint", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, package: {name: openssl, version: '~> 1.1'}}]"},
+		{"absent package with a version", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, package: {name: telnet, absent: true, version: '>= 1.0'}}]"},
+		{"absent file with a mode", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, file: {path: /etc/shadow, absent: true, mode: '0600'}}]"},
+		{"absent file with content", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, file: {path: /etc/motd, absent: true, contentMatching: hello}}]"},
+		{"enabled and ignoreEnabled", "targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, service: {name: app, enabled: true, ignoreEnabled: true}}]"},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if _, err := ParseSuite([]byte(c.suite)); err == nil {
+				t.Errorf("Expected an error, but got nil")
+			}
+		})
+	}
+}
+
+func TestParseSuiteJson(t *testing.T) {
+	suite, err := ParseSuite([]byte(`{"targets": {"app": {"tag": {"Name": "app"}}}, "tests": [{"name": "a", "target": "app", "http": {"url": "http://localhost:8080/health", "status": 204}}]}`))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	testCase, _, _, _ := suite.build(suite.Tests[0])
+	if e, a := NewHttpTestCase("http://localhost:8080/health").WithStatus(204), testCase; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the test case to be %v, but got %v", e, a)
+	}
+}
+
+func TestParseSuiteIgnoreEnabled(t *testing.T) {
+	suite, err := ParseSuite([]byte("targets: {app: {tag: {Name: app}}}\ntests: [{name: a, target: app, service: {name: app, ignoreEnabled: true}}]"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	testCase, _, _, _ := suite.build(suite.Tests[0])
+	if e, a := NewServiceTestCase("app").WithoutEnabledCheck(), testCase; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the test case to be %v, but got %v", e, a)
+	}
+}
+
+func TestInvertedTestCase(t *testing.T) {
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"output=$(",
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`response=$(curl -s --max-time 5 -w '\n%{http_code}' 'http://localhost')`,
+			`status=$(echo "$response" | tail -n 1); body=$(echo "$response" | sed '$d')`,
+			`echo "status=$status"`,
+			`if [ "$status" = 000 ]; then echo "FAIL: no response from "'http://localhost'; exit 1; fi`,
+			`[ "$status" = '200' ] || fail 'status is' "$status" 'expected' '200'`,
+			"exit $rc",
+			")",
+			"status=$?",
+			`[ -z "$output" ] || echo "$output" | sed 's/^FAIL: /expected failure: /'`,
+			`if [ $status -eq 0 ]; then echo "FAIL: expected the test to fail"; exit 1; fi`,
+			"exit 0",
+		},
+	}
+	testCase := invertedTestCase{NewHttpTestCase("http://localhost")}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestInvertedTestCaseOutput(t *testing.T) {
+	cases := []struct {
+		caseName         string
+		status           types.CommandInvocationStatus
+		output           string
+		expectedPassed   bool
+		expectedFailures []string
+	}{
+		{"failing test case", types.CommandInvocationStatusSuccess, "status=000\nexpected failure: no response\n", true, nil},
+		{"passing test case", types.CommandInvocationStatusFailed, "status=200\nFAIL: expected the test to fail\n", false,
+			[]string{"expected the test to fail"}},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			testCase := invertedTestCase{NewHttpTestCase("http://localhost")}
+			var commands []string
+			client := &mockClient{
+				mockSendCommand: func(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+					commands = params.Parameters["commands"]
+					return &ssm.SendCommandOutput{Command: &types.Command{CommandId: stringPointer("command-id")}}, nil
+				},
+				mockListCommandInvocations: []func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error){
+					func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error) {
+						return &ssm.ListCommandInvocationsOutput{CommandInvocations: []types.CommandInvocation{{
+							InstanceId:     stringPointer("i-1"),
+							Status:         c.status,
+							CommandPlugins: []types.CommandPlugin{{Output: stringPointer(c.output)}},
+						}}}, nil
+					},
+				},
+			}
+			result, _ := RunTestCaseForTargetWithResultsE(t, client, testCase, NewTagNameTarget("app"), NewRetryConfig(0, 0))
+			if e, a := testCase.buildCommandParameters()["commands"], commands; !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the commands sent to be %v, but got %v", e, a)
+			}
+			if e, a := c.expectedPassed, result.Passed(); e != a {
+				t.Errorf("Expected passed to be %v, but got %v", e, a)
+			}
+			if len(result.Invocations) != 1 {
+				t.Fatalf("Expected 1 invocation, but got %v", result.Invocations)
+			}
+			if e, a := c.expectedFailures, result.Invocations[0].Failures(); !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the failures to be %v, but got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestRunSuite(t *testing.T) {
+	suite, err := ParseSuite([]byte(testSuite))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	var documents []string
+	client := &mockClient{
+		mockSendCommand: func(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+			documents = append(documents, *params.DocumentName)
+			return &ssm.SendCommandOutput{Command: &types.Command{CommandId: stringPointer("command-id")}}, nil
+		},
+		mockListCommandInvocations: []func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error){
+			func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error) {
+				return &ssm.ListCommandInvocationsOutput{CommandInvocations: []types.CommandInvocation{{
+					InstanceId: stringPointer("i-1"),
+					Status:     types.CommandInvocationStatusSuccess,
+				}}}, nil
+			},
+		},
+	}
+	suite.Retry = &SuiteRetry{}
+	for i := range suite.Tests {
+		suite.Tests[i].Retry = nil
+	}
+	RunSuite(t, client, suite)
+	if e, a := []string{"AWS-RunShellScript", "AWS-RunShellScript", "AWS-RunShellScript"}, documents; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the documents sent to be %v, but got %v", e, a)
+	}
+}
+
+func TestSuiteFilter(t *testing.T) {
+	suite, err := ParseSuite([]byte(testSuite))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	filtered, err := suite.Filter("^app ")
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if e, a := []SuiteTest{suite.Tests[0], suite.Tests[1]}, filtered.Tests; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the tests to be %v, but got %v", e, a)
+	}
+	if e, a := 3, len(suite.Tests); e != a {
+		t.Errorf("Expected the suite to still have %d tests, but got %d", e, a)
+	}
+}
+
+func TestRunSuiteE(t *testing.T) {
+	suite, err := ParseSuite([]byte(testSuite))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	suite.Retry = &SuiteRetry{}
+	for i := range suite.Tests {
+		suite.Tests[i].Retry = nil
+	}
+	client := &mockClient{
+		mockSendCommand: func(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+			return &ssm.SendCommandOutput{Command: &types.Command{CommandId: stringPointer(params.Targets[0].Values[0])}}, nil
+		},
+		mockListCommandInvocations: []func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error){
+			func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error) {
+				// the tests of the app target fail
+				status := types.CommandInvocationStatusSuccess
+				if *params.CommandId == "app-instance" {
+					status = types.CommandInvocationStatusFailed
+				}
+				return &ssm.ListCommandInvocationsOutput{CommandInvocations: []types.CommandInvocation{{
+					InstanceId: stringPointer("i-1"),
+					Status:     status,
+				}}}, nil
+			},
+		},
+	}
+	result, err := RunSuiteE(client, suite)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	var names []string
+	var passed []bool
+	for _, v := range result.Tests {
+		names = append(names, v.Name)
+		passed = append(passed, v.Passed)
+		if e, a := v.Passed, v.Err == nil; e != a {
+			t.Errorf("Expected the test %s to have an error %v, but got %v", v.Name, !e, v.Err)
+		}
+	}
+	if e, a := []string{"app can connect to the database", "app cannot connect to the internet", "workers do not have telnet"}, names; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the tests run to be %v, but got %v", e, a)
+	}
+	if e, a := []bool{false, false, true}, passed; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the tests passed to be %v, but got %v", e, a)
+	}
+	if result.Passed() {
+		t.Errorf("Expected the suite not to pass")
+	}
+}
@@ -1,6 +1,13 @@
 package tester
 
-import "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"strings"
+)
 
 // TagNameTarget fulfills the tagParamBuilder interface for targets instances to be selected by the tag:Name
 type TagNameTarget struct {
@@ -19,3 +26,120 @@ func NewTagNameTarget(tagNameValue string) TagNameTarget {
 		tagNameValue: tagNameValue,
 	}
 }
+
+// TagTarget fulfills the targetParamBuilder interface for target instances to be selected by the value of any tag.
+type TagTarget struct {
+	key    string   // the key of the tag
+	values []string // the values of the tag, any of which selects an instance
+}
+
+func (tt TagTarget) buildTargetParameters() []types.Target {
+	return []types.Target{{Key: stringPointer("tag:" + tt.key), Values: tt.values}}
+}
+
+// NewTagTarget returns an instance of TagTarget that can be supplied to the tester.
+// key should be the key of the tag, and values the values of the tag of the instances to be targeted eg.
+// NewTagTarget("Environment", "staging").
+func NewTagTarget(key string, values ...string) TagTarget {
+	return TagTarget{
+		key:    key,
+		values: values,
+	}
+}
+
+// NewAutoScalingGroupTarget returns a TagTarget for the instances of the auto scaling group name, by the
+// aws:autoscaling:groupName tag that auto scaling adds to its instances.
+func NewAutoScalingGroupTarget(name string) TagTarget {
+	return NewTagTarget("aws:autoscaling:groupName", name)
+}
+
+// InstanceIdsTarget fulfills the targetParamBuilder interface for target instances to be selected by their instance ids.
+type InstanceIdsTarget struct {
+	instanceIds []string // the ids of the instances
+}
+
+func (iit InstanceIdsTarget) buildTargetParameters() []types.Target {
+	return []types.Target{{Key: stringPointer("InstanceIds"), Values: iit.instanceIds}}
+}
+
+// NewInstanceIdsTarget returns an instance of InstanceIdsTarget that can be supplied to the tester.
+// instanceIds should be the ids of the instances to be targeted eg. "i-0123456789abcdef0".
+func NewInstanceIdsTarget(instanceIds ...string) InstanceIdsTarget {
+	return InstanceIdsTarget{
+		instanceIds: instanceIds,
+	}
+}
+
+// TargetInstance is a running EC2 instance selected by a target.
+type TargetInstance struct {
+	InstanceId       string
+	PrivateIpAddress string
+	Name             string // the value of the Name tag of the instance, empty if not tagged
+}
+
+// DescribeTargetInstancesE returns the running EC2 instances selected by target, as found with the EC2 DescribeInstances
+// API, eg. to resolve the private IP addresses of the instances. Targets by instance ids, tag:<key> and tag-key are
+// supported, which are all the targets the tester provides.
+func DescribeTargetInstancesE(client instanceDescriber, target targetParamBuilder) ([]TargetInstance, error) {
+	input, err := buildDescribeInstancesInput(target.buildTargetParameters())
+	if err != nil {
+		return nil, err
+	}
+	var instances []TargetInstance
+	for {
+		output, err := client.DescribeInstances(context.Background(), input)
+		if err != nil {
+			return nil, err
+		}
+		for _, reservation := range output.Reservations {
+			for _, v := range reservation.Instances {
+				instance := TargetInstance{}
+				if v.InstanceId != nil {
+					instance.InstanceId = *v.InstanceId
+				}
+				if v.PrivateIpAddress != nil {
+					instance.PrivateIpAddress = *v.PrivateIpAddress
+				}
+				for _, tag := range v.Tags {
+					if tag.Key != nil && *tag.Key == "Name" && tag.Value != nil {
+						instance.Name = *tag.Value
+					}
+				}
+				instances = append(instances, instance)
+			}
+		}
+		if input.NextToken = output.NextToken; input.NextToken == nil {
+			break
+		}
+	}
+	return instances, nil
+}
+
+// buildDescribeInstancesInput converts the SSM targets to the equivalent EC2 DescribeInstances filters.
+func buildDescribeInstancesInput(targets []types.Target) (*ec2.DescribeInstancesInput, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{{Name: stringPointer("instance-state-name"), Values: []string{"running"}}},
+	}
+	for _, v := range targets {
+		if v.Key == nil {
+			continue
+		}
+		switch key := *v.Key; {
+		case key == "InstanceIds":
+			input.InstanceIds = append(input.InstanceIds, v.Values...)
+		case strings.HasPrefix(key, "tag:"), key == "tag-key":
+			input.Filters = append(input.Filters, ec2types.Filter{Name: stringPointer(key), Values: v.Values})
+		default:
+			return nil, unsupportedTargetKeyError{key: key}
+		}
+	}
+	return input, nil
+}
+
+type unsupportedTargetKeyError struct {
+	key string
+}
+
+func (err unsupportedTargetKeyError) Error() string {
+	return fmt.Sprintf("target key %s cannot be resolved to instances", err.key)
+}
@@ -1,6 +1,9 @@
 package tester
 
 import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
 	"reflect"
 	"testing"
@@ -29,3 +32,123 @@ func TestTagNameTarget(t *testing.T) {
 		})
 	}
 }
+
+func TestTargets(t *testing.T) {
+	cases := []struct {
+		caseName string
+		target   targetParamBuilder
+		expected []types.Target
+	}{
+		{
+			"tag",
+			NewTagTarget("Environment", "staging", "dev"),
+			[]types.Target{{Key: stringPointer("tag:Environment"), Values: []string{"staging", "dev"}}},
+		},
+		{
+			"auto scaling group",
+			NewAutoScalingGroupTarget("workers"),
+			[]types.Target{{Key: stringPointer("tag:aws:autoscaling:groupName"), Values: []string{"workers"}}},
+		},
+		{
+			"instance ids",
+			NewInstanceIdsTarget("i-1", "i-2"),
+			[]types.Target{{Key: stringPointer("InstanceIds"), Values: []string{"i-1", "i-2"}}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if e, a := c.expected, c.target.buildTargetParameters(); !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected %v, but got %v", e, a)
+			}
+		})
+	}
+}
+
+// Mock that satisfies the instanceDescriber interface
+type mockInstanceDescriber struct {
+	mockDescribeInstances func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+}
+
+func (m mockInstanceDescriber) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return m.mockDescribeInstances(ctx, params, optFns...)
+}
+
+func TestBuildDescribeInstancesInput(t *testing.T) {
+	running := ec2types.Filter{Name: stringPointer("instance-state-name"), Values: []string{"running"}}
+	cases := []struct {
+		caseName      string
+		targets       []types.Target
+		expected      *ec2.DescribeInstancesInput
+		expectedError error
+	}{
+		{
+			"tag name",
+			NewTagNameTarget("app").buildTargetParameters(),
+			&ec2.DescribeInstancesInput{Filters: []ec2types.Filter{running, {Name: stringPointer("tag:Name"), Values: []string{"app"}}}},
+			nil,
+		},
+		{
+			"instance ids and tag key",
+			[]types.Target{
+				{Key: stringPointer("InstanceIds"), Values: []string{"i-1", "i-2"}},
+				{Key: stringPointer("tag-key"), Values: []string{"team"}},
+			},
+			&ec2.DescribeInstancesInput{
+				InstanceIds: []string{"i-1", "i-2"},
+				Filters:     []ec2types.Filter{running, {Name: stringPointer("tag-key"), Values: []string{"team"}}},
+			},
+			nil,
+		},
+		{
+			"resource group",
+			[]types.Target{{Key: stringPointer("resource-groups:Name"), Values: []string{"web"}}},
+			nil,
+			unsupportedTargetKeyError{key: "resource-groups:Name"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			input, err := buildDescribeInstancesInput(c.targets)
+			if e, a := c.expectedError, err; !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected error %v, but got %v", e, a)
+			}
+			if e, a := c.expected, input; !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected %v, but got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestDescribeTargetInstancesE(t *testing.T) {
+	client := mockInstanceDescriber{
+		mockDescribeInstances: func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			if params.NextToken == nil {
+				return &ec2.DescribeInstancesOutput{
+					Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{{
+						InstanceId:       stringPointer("i-1"),
+						PrivateIpAddress: stringPointer("10.0.0.1"),
+						Tags:             []ec2types.Tag{{Key: stringPointer("Name"), Value: stringPointer("app")}},
+					}}}},
+					NextToken: stringPointer("page2"),
+				}, nil
+			}
+			return &ec2.DescribeInstancesOutput{
+				Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{{
+					InstanceId:       stringPointer("i-2"),
+					PrivateIpAddress: stringPointer("10.0.0.2"),
+				}}}},
+			}, nil
+		},
+	}
+	instances, err := DescribeTargetInstancesE(client, NewTagNameTarget("app"))
+	if err != nil {
+		t.Errorf("Expected no error, but got %v", err)
+	}
+	expected := []TargetInstance{
+		{InstanceId: "i-1", PrivateIpAddress: "10.0.0.1", Name: "app"},
+		{InstanceId: "i-2", PrivateIpAddress: "10.0.0.2"},
+	}
+	if e, a := expected, instances; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected %v, but got %v", e, a)
+	}
+}
@@ -0,0 +1,155 @@
+package tester
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TcpLatencyTestCase configuration for a test that measures the TCP connect latency from the target instances to an
+// endpoint over a number of samples, and optionally the download throughput from a url, eg. to confirm cross-AZ or
+// Direct Connect paths meet their SLOs rather than only that they connect.
+// The test fails on an instance if any connection fails, or if a measured value exceeds its threshold. The measured
+// values for each instance are available from the TestResult with Measurements.
+//
+// Connect times are measured by curl, so they do not include process start up. curl makes an HTTP request once
+// connected, which fails harmlessly against other protocols, but a server that waits silently for the client delays
+// each sample up to the timeout.
+type TcpLatencyTestCase struct {
+	runShellScript
+	endpoint         string        // the endpoint to connect to
+	port             int           // the port to connect to
+	samples          int           // the number of connections to make
+	maxP50           time.Duration // the maximum median connect latency, not checked if 0
+	maxP95           time.Duration // the maximum 95th percentile connect latency, not checked if 0
+	throughputUrl    string        // the url to download to measure throughput, not measured if empty
+	minThroughput    float64       // the minimum download throughput in bytes per second
+	timeoutInSeconds int           // the time to wait for each connection
+	// the time to let the download run, it is stopped after this time and the throughput measured over the bytes received
+	throughputTimeoutInSeconds int
+}
+
+// LatencyMeasurement are the values measured on a target instance by a TcpLatencyTestCase.
+type LatencyMeasurement struct {
+	Samples    int           // the number of connections made
+	Failed     int           // the number of connections that failed
+	P50        time.Duration // the median connect latency of the successful connections
+	P95        time.Duration // the 95th percentile connect latency of the successful connections
+	Throughput float64       // the download throughput in bytes per second, 0 if not measured
+}
+
+func (tltc TcpLatencyTestCase) buildCommandParameters() map[string][]string {
+	url := shellQuote(fmt.Sprintf("http://%s:%d/", tltc.endpoint, tltc.port))
+	assertions := []string{
+		`latencies=""; failed=0; i=0`,
+		fmt.Sprintf(`while [ $i -lt %d ]; do connect=$(curl -s -o /dev/null --max-time %d -w '%%{time_connect}' %s); case "$connect" in ""|0|0.000000) failed=$((failed + 1)) ;; *) latencies="$latencies $connect" ;; esac; i=$((i + 1)); done`,
+			tltc.samples, tltc.timeoutInSeconds, url),
+		// nearest rank percentiles of the successful connections, in milliseconds
+		`percentiles=$(echo $latencies | tr ' ' '\n' | sort -n | awk 'NF { v[++n] = $1 * 1000 } END { if (n) printf "%.3f %.3f", v[int((n * 50 + 99) / 100)], v[int((n * 95 + 99) / 100)] }')`,
+		`p50=${percentiles%% *}; p95=${percentiles#* }`,
+		fmt.Sprintf(`echo "samples=%d"; echo "failed_samples=$failed"; echo "latency_p50_ms=$p50"; echo "latency_p95_ms=$p95"`, tltc.samples),
+		fmt.Sprintf(`[ "$failed" -eq 0 ] || fail "$failed" 'of %d connections to' %s 'failed'`, tltc.samples, shellQuote(fmt.Sprintf("%s:%d", tltc.endpoint, tltc.port))),
+	}
+	if tltc.maxP50 > 0 {
+		assertions = append(assertions, expectAtMost("p50 latency", "$p50", "ms", durationInMilliseconds(tltc.maxP50)))
+	}
+	if tltc.maxP95 > 0 {
+		assertions = append(assertions, expectAtMost("p95 latency", "$p95", "ms", durationInMilliseconds(tltc.maxP95)))
+	}
+	if tltc.throughputUrl != "" {
+		minThroughput := strconv.FormatFloat(tltc.minThroughput, 'f', -1, 64)
+		assertions = append(assertions,
+			// curl reports the speed of a download stopped by --max-time, so only missing output is a failed download
+			fmt.Sprintf(`throughput=$(curl -s -o /dev/null --max-time %d -w '%%{speed_download}' %s); [ -n "$throughput" ] || throughput=0`,
+				tltc.throughputTimeoutInSeconds, shellQuote(tltc.throughputUrl)),
+			`echo "throughput_bytes_per_second=$throughput"`,
+			fmt.Sprintf(`awk -v v="$throughput" 'BEGIN { exit !(v >= %s) }' || fail 'throughput is' "$throughput" 'bytes per second expected at least %s'`,
+				minThroughput, minThroughput),
+		)
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// expectAtMost fails if the measured value is missing or greater than max, like expectEqual for numbers.
+func expectAtMost(name string, value string, unit string, max string) string {
+	return fmt.Sprintf(`awk -v v="%s" 'BEGIN { exit !(v != "" && v <= %s) }' || fail %s "%s" %s`,
+		value, max, shellQuote(name+" is"), value, shellQuote(fmt.Sprintf("%s expected at most %s%s", unit, max, unit)))
+}
+
+func durationInMilliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
+
+// WithMaxP50 returns a copy of the TcpLatencyTestCase that checks the median connect latency is at most max.
+func (tltc TcpLatencyTestCase) WithMaxP50(max time.Duration) TcpLatencyTestCase {
+	tltc.maxP50 = max
+	return tltc
+}
+
+// WithMaxP95 returns a copy of the TcpLatencyTestCase that checks the 95th percentile connect latency is at most max.
+func (tltc TcpLatencyTestCase) WithMaxP95(max time.Duration) TcpLatencyTestCase {
+	tltc.maxP95 = max
+	return tltc
+}
+
+// WithSamples returns a copy of the TcpLatencyTestCase that makes samples connections.
+func (tltc TcpLatencyTestCase) WithSamples(samples int) TcpLatencyTestCase {
+	tltc.samples = samples
+	return tltc
+}
+
+// WithThroughput returns a copy of the TcpLatencyTestCase that also downloads url, and checks the download throughput is
+// at least minBytesPerSecond. url should serve a file large enough for the transfer to take at least a few seconds.
+// The download is stopped after the throughput timeout, 30 seconds by default, and the throughput measured over the
+// bytes received until then.
+func (tltc TcpLatencyTestCase) WithThroughput(url string, minBytesPerSecond float64) TcpLatencyTestCase {
+	tltc.throughputUrl = url
+	tltc.minThroughput = minBytesPerSecond
+	return tltc
+}
+
+// WithTimeout returns a copy of the TcpLatencyTestCase that waits timeoutInSeconds for each connection.
+func (tltc TcpLatencyTestCase) WithTimeout(timeoutInSeconds int) TcpLatencyTestCase {
+	tltc.timeoutInSeconds = timeoutInSeconds
+	return tltc
+}
+
+// WithThroughputTimeout returns a copy of the TcpLatencyTestCase that lets the throughput download run for at most
+// timeoutInSeconds.
+func (tltc TcpLatencyTestCase) WithThroughputTimeout(timeoutInSeconds int) TcpLatencyTestCase {
+	tltc.throughputTimeoutInSeconds = timeoutInSeconds
+	return tltc
+}
+
+// Measurements returns the values measured on each instance in result, keyed by instance id.
+func (tltc TcpLatencyTestCase) Measurements(result TestResult) map[string]LatencyMeasurement {
+	measurements := map[string]LatencyMeasurement{}
+	for _, v := range result.Invocations {
+		values := v.Values()
+		measurement := LatencyMeasurement{}
+		measurement.Samples, _ = strconv.Atoi(values["samples"])
+		measurement.Failed, _ = strconv.Atoi(values["failed_samples"])
+		if ms, err := strconv.ParseFloat(values["latency_p50_ms"], 64); err == nil {
+			measurement.P50 = time.Duration(ms * float64(time.Millisecond))
+		}
+		if ms, err := strconv.ParseFloat(values["latency_p95_ms"], 64); err == nil {
+			measurement.P95 = time.Duration(ms * float64(time.Millisecond))
+		}
+		measurement.Throughput, _ = strconv.ParseFloat(values["throughput_bytes_per_second"], 64)
+		measurements[v.InstanceId] = measurement
+	}
+	return measurements
+}
+
+// NewTcpLatencyTestCase is a constructor for TcpLatencyTestCase type.
+// endpoint and port should be the network endpoint to measure the connect latency to. By default 10 samples are taken
+// and only failed connections fail the test, thresholds are added with the With methods.
+func NewTcpLatencyTestCase(endpoint string, port int) TcpLatencyTestCase {
+	return TcpLatencyTestCase{
+		endpoint:                   endpoint,
+		port:                       port,
+		samples:                    10,
+		timeoutInSeconds:           5,
+		throughputTimeoutInSeconds: 30,
+	}
+}
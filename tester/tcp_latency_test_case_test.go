@@ -0,0 +1,53 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTcpLatencyTestCase(t *testing.T) {
+	testCase := NewTcpLatencyTestCase("10.100.0.10", 5432).
+		WithSamples(20).
+		WithMaxP50(2*time.Millisecond).
+		WithMaxP95(2500*time.Microsecond).
+		WithThroughput("http://10.100.0.10/100MB.bin", 50e6).
+		WithThroughputTimeout(10)
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`latencies=""; failed=0; i=0`,
+			`while [ $i -lt 20 ]; do connect=$(curl -s -o /dev/null --max-time 5 -w '%{time_connect}' 'http://10.100.0.10:5432/'); case "$connect" in ""|0|0.000000) failed=$((failed + 1)) ;; *) latencies="$latencies $connect" ;; esac; i=$((i + 1)); done`,
+			`percentiles=$(echo $latencies | tr ' ' '\n' | sort -n | awk 'NF { v[++n] = $1 * 1000 } END { if (n) printf "%.3f %.3f", v[int((n * 50 + 99) / 100)], v[int((n * 95 + 99) / 100)] }')`,
+			`p50=${percentiles%% *}; p95=${percentiles#* }`,
+			`echo "samples=20"; echo "failed_samples=$failed"; echo "latency_p50_ms=$p50"; echo "latency_p95_ms=$p95"`,
+			`[ "$failed" -eq 0 ] || fail "$failed" 'of 20 connections to' '10.100.0.10:5432' 'failed'`,
+			`awk -v v="$p50" 'BEGIN { exit !(v != "" && v <= 2) }' || fail 'p50 latency is' "$p50" 'ms expected at most 2ms'`,
+			`awk -v v="$p95" 'BEGIN { exit !(v != "" && v <= 2.5) }' || fail 'p95 latency is' "$p95" 'ms expected at most 2.5ms'`,
+			`throughput=$(curl -s -o /dev/null --max-time 10 -w '%{speed_download}' 'http://10.100.0.10/100MB.bin'); [ -n "$throughput" ] || throughput=0`,
+			`echo "throughput_bytes_per_second=$throughput"`,
+			`awk -v v="$throughput" 'BEGIN { exit !(v >= 50000000) }' || fail 'throughput is' "$throughput" 'bytes per second expected at least 50000000'`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, testCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestTcpLatencyTestCaseMeasurements(t *testing.T) {
+	result := TestResult{
+		Invocations: []InvocationResult{
+			{InstanceId: "i-1", Output: "samples=10\nfailed_samples=0\nlatency_p50_ms=0.261\nlatency_p95_ms=1.500\nthroughput_bytes_per_second=1250000.000\n"},
+			{InstanceId: "i-2", Output: "samples=10\nfailed_samples=10\nlatency_p50_ms=\nlatency_p95_ms=\n"},
+		},
+	}
+	expected := map[string]LatencyMeasurement{
+		"i-1": {Samples: 10, Failed: 0, P50: 261 * time.Microsecond, P95: 1500 * time.Microsecond, Throughput: 1250000},
+		"i-2": {Samples: 10, Failed: 10},
+	}
+	if e, a := expected, NewTcpLatencyTestCase("10.100.0.10", 5432).Measurements(result); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the measurements to be %v, but got %v", e, a)
+	}
+}
@@ -35,19 +35,47 @@ func RunTestCaseForTarget(t *testing.T, client commandSenderLister, testCase com
 // It returns false and error for any other error.
 func RunTestCaseForTargetE(t *testing.T, client commandSenderLister, testCase commandParameterBuilder, target targetParamBuilder,
 	retryConfig RetryConfig) (bool, error) {
+	result, err := RunTestCaseForTargetWithResultsE(t, client, testCase, target, retryConfig)
+	if err != nil {
+		return false, err
+	}
+	return result.Passed(), nil
+}
+
+// RunTestCaseForTargetWithResults is like RunTestCaseForTarget but also returns the TestResult, with the status and
+// output of the test command on each of the target instances.
+func RunTestCaseForTargetWithResults(t *testing.T, client commandSenderLister, testCase commandParameterBuilder, target targetParamBuilder,
+	retryConfig RetryConfig) TestResult {
+	result, err := RunTestCaseForTargetWithResultsE(t, client, testCase, target, retryConfig)
+	if err != nil {
+		t.Error(err)
+	}
+	return result
+}
+
+// RunTestCaseForTargetWithResultsE is like RunTestCaseForTargetE but returns the TestResult instead of a bool.
+// The TestResult contains the invocations found on the last poll of the AWS SSM API, so it is populated for failed tests
+// as well, and may be used to report on the state of each of the target instances.
+// t may be nil when the test case is run outside of go test, eg. from a command line tool.
+func RunTestCaseForTargetWithResultsE(t *testing.T, client commandSenderLister, testCase commandParameterBuilder, target targetParamBuilder,
+	retryConfig RetryConfig) (TestResult, error) {
 	// send test command
 	sendCommandInput := newSendCommandInput(testCase, target)
 	sendCommandOutput, err := client.SendCommand(context.Background(), sendCommandInput)
 	if err != nil {
-		return false, err
+		return TestResult{}, err
 	}
+	commandId := *sendCommandOutput.Command.CommandId
+	logger := retryConfig.loggerFor(t)
+	logger.Debug("sent test command", "commandId", commandId, "document", *sendCommandInput.DocumentName,
+		"commands", sendCommandInput.Parameters["commands"])
 	// poll for test command execution results
-	retryAction := getListCommandAction(t, client, *sendCommandOutput.Command.CommandId)
-	result, err := retry(t, "Poll For Invocation Results", retryConfig.maxRetries, retryConfig.waitBetweenRetries, retryAction)
-	if err != nil {
-		return false, err
-	}
-	return result.(bool), nil
+	retryAction := getListCommandAction(client, commandId)
+	output, err := retry(logger, "Poll For Invocation Results", retryConfig.maxRetries, retryConfig.waitBetweenRetries, retryAction,
+		"commandId", commandId)
+	result, _ := output.(TestResult)
+	result.CommandId = commandId
+	return result, err
 }
 
 func newSendCommandInput(testCase commandParameterBuilder, target targetParamBuilder) *ssm.SendCommandInput {
@@ -59,63 +87,85 @@ func newSendCommandInput(testCase commandParameterBuilder, target targetParamBui
 	}
 }
 
-func buildListCommandInput(commandId string) *ssm.ListCommandInvocationsInput {
+func buildListCommandInput(commandId string, nextToken *string) *ssm.ListCommandInvocationsInput {
 	return &ssm.ListCommandInvocationsInput{
 		CommandId: &commandId,
+		Details:   true,
+		NextToken: nextToken,
 	}
 }
 
-func getListCommandAction(t *testing.T, client commandLister, commandId string) func() (interface{}, error) {
+func getListCommandAction(client commandLister, commandId string) func() (interface{}, error) {
 	return func() (interface{}, error) {
-		listCommandOutput, err := client.ListCommandInvocations(context.Background(), buildListCommandInput(commandId))
-		if err != nil {
-			t.Error(err)
+		var invocations []types.CommandInvocation
+		var nextToken *string
+		for {
+			listCommandOutput, err := client.ListCommandInvocations(context.Background(), buildListCommandInput(commandId, nextToken))
+			if err != nil {
+				return nil, err
+			}
+			invocations = append(invocations, listCommandOutput.CommandInvocations...)
+			if nextToken = listCommandOutput.NextToken; nextToken == nil {
+				break
+			}
 		}
-		if len(listCommandOutput.CommandInvocations) == 0 {
+		if len(invocations) == 0 {
 			// Todo Log message to help debugging
 			return nil, noInvocationFoundError{}
 		}
 
-		//Check status of all found invocations
-		result, err := checkAllInvocationForStatus(listCommandOutput)
-		if err != nil {
-			return nil, err
+		result := TestResult{CommandId: commandId}
+		for _, v := range invocations {
+			result.Invocations = append(result.Invocations, newInvocationResult(v))
 		}
-		return result, nil
+		//Check status of all found invocations
+		return result, checkAllInvocationForStatus(result)
 	}
 }
 
-// Returns true if all the invocations have succeeded.
-// Returns false if any of the invocations has failed
+// Returns nil if all the invocations have succeeded.
 // Returns an error, signalling to the retry function to try again in the case of pending, in progress or delayed invocation
-func checkAllInvocationForStatus(listCommandOutput *ssm.ListCommandInvocationsOutput) (bool, error) {
-	for _, v := range listCommandOutput.CommandInvocations {
+// Returns a fatal error if all the invocations have completed and any of them has failed.
+// All invocations are waited on before reporting a failure, so that the result is available for every instance.
+func checkAllInvocationForStatus(result TestResult) error {
+	pending := 0
+	for _, v := range result.Invocations {
 		switch v.Status {
 		case types.CommandInvocationStatusPending,
 			types.CommandInvocationStatusInProgress,
 			types.CommandInvocationStatusDelayed:
-			// Todo log message about why retry
-			return false, invocationsIncompleteError{}
+			pending++
+		}
+	}
+	if pending > 0 {
+		return invocationsIncompleteError{pending: pending}
+	}
+	for _, v := range result.Invocations {
+		switch v.Status {
 		case types.CommandInvocationStatusFailed,
 			types.CommandInvocationStatusCancelled,
 			types.CommandInvocationStatusCancelling,
 			types.CommandInvocationStatusTimedOut:
-			// Todo log message to help debug failure
-			// return false and nil error to signal retry to stop and to return false to the user
-			return false, fatalError{Underlying: failedForInstanceIdError{instanceId: *v.InstanceId}}
+			// return a fatal error to signal retry to stop and to return false to the user
+			return fatalError{Underlying: failedForInstanceIdError{instanceId: v.InstanceId, output: v.Output}}
 		}
 	}
 	// In the case that all the invocations were Successful
-	return true, nil
+	return nil
 }
 
 type invocationsIncompleteError struct {
+	pending int // the number of invocations not yet complete
 }
 
 func (err invocationsIncompleteError) Error() string {
 	return "command invocations pending, inprogress or delayed"
 }
 
+func (err invocationsIncompleteError) logArgs() []interface{} {
+	return []interface{}{"pending", err.pending}
+}
+
 type noInvocationFoundError struct {
 }
 
@@ -125,10 +175,14 @@ func (err noInvocationFoundError) Error() string {
 
 type failedForInstanceIdError struct {
 	instanceId string
+	output     string
 }
 
 func (err failedForInstanceIdError) Error() string {
-	return fmt.Sprintf("command invocations failed for instanceId %s", err.instanceId)
+	if err.output == "" {
+		return fmt.Sprintf("command invocations failed for instanceId %s", err.instanceId)
+	}
+	return fmt.Sprintf("command invocations failed for instanceId %s with output:\n%s", err.instanceId, err.output)
 }
 
 // RetryConfig contains configuration for the testers retry logic while polling AWS SSM API for test results.
@@ -136,6 +190,24 @@ func (err failedForInstanceIdError) Error() string {
 type RetryConfig struct {
 	maxRetries         int
 	waitBetweenRetries time.Duration
+	logger             Logger // the logger to report progress to, t.Logf if nil
+}
+
+// WithLogger returns a copy of the RetryConfig that reports the progress of the test to logger, instead of t.Logf.
+func (rc RetryConfig) WithLogger(logger Logger) RetryConfig {
+	rc.logger = logger
+	return rc
+}
+
+// loggerFor returns the Logger of the RetryConfig, or a Logger for t if it has none.
+func (rc RetryConfig) loggerFor(t *testing.T) Logger {
+	if rc.logger != nil {
+		return rc.logger
+	}
+	if t == nil {
+		return discardLogger{}
+	}
+	return NewTestLogger(t, LevelDebug)
 }
 
 // NewRetryConfig returns a instance of RetryConfig where
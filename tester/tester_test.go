@@ -134,7 +134,7 @@ func TestRunTestCaseForTargetE(t *testing.T) {
 			target:        NewTagNameTarget("ec2NameTag"),
 			testCase:      NewShellTestCase("echo lol", true),
 			expected:      false,
-			expectedError: maxRetriesExceededError{underlying: invocationsIncompleteError{}},
+			expectedError: maxRetriesExceededError{underlying: invocationsIncompleteError{pending: 3}},
 		},
 		{
 			caseName: "Success should be returned if command invocation completes successfully after retry",
@@ -236,3 +236,47 @@ func TestRunTestCaseForTargetE(t *testing.T) {
 	}
 
 }
+
+func TestRunTestCaseForTargetWithResultsE(t *testing.T) {
+	client := &mockClient{
+		mockSendCommand: mockSendCommandHelper(t, NewTagNameTarget("ec2NameTag"), NewShellTestCase("echo lol", true)),
+		mockListCommandInvocations: []func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (output *ssm.ListCommandInvocationsOutput, e error){
+			func(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (output *ssm.ListCommandInvocationsOutput, e error) {
+				// Details should be requested so that the output of the invocations is returned
+				if !params.Details {
+					t.Errorf("Expected Details to be requested")
+				}
+				return &ssm.ListCommandInvocationsOutput{
+					CommandInvocations: []types.CommandInvocation{
+						{
+							InstanceId:     stringPointer("dummyInstanceId"),
+							Status:         types.CommandInvocationStatusFailed,
+							CommandPlugins: []types.CommandPlugin{{Output: stringPointer("FAIL: lol")}},
+						},
+						{
+							InstanceId:     stringPointer("dummyInstanceId2"),
+							Status:         types.CommandInvocationStatusSuccess,
+							CommandPlugins: []types.CommandPlugin{{Output: stringPointer("lol")}},
+						},
+					},
+				}, nil
+			},
+		},
+	}
+	expected := TestResult{
+		CommandId: "dummyCommandId",
+		Invocations: []InvocationResult{
+			{InstanceId: "dummyInstanceId", Status: types.CommandInvocationStatusFailed, Output: "FAIL: lol"},
+			{InstanceId: "dummyInstanceId2", Status: types.CommandInvocationStatusSuccess, Output: "lol"},
+		},
+	}
+	expectedError := fatalError{Underlying: failedForInstanceIdError{instanceId: "dummyInstanceId", output: "FAIL: lol"}}
+
+	actual, actualErr := RunTestCaseForTargetWithResultsE(t, client, NewShellTestCase("echo lol", true), NewTagNameTarget("ec2NameTag"), NewRetryConfig(5, 1))
+	if actualErr == nil || actualErr.Error() != expectedError.Error() {
+		t.Errorf("Expected error %v, but got %v", expectedError, actualErr)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v, but got %v", expected, actual)
+	}
+}
@@ -0,0 +1,349 @@
+// Package testerfake provides an in-memory fake of the AWS SSM API, so that code built on the tester package can be unit
+// tested offline, without AWS credentials or instances.
+//
+// A Client simulates a fleet of instances with tags, matches the targets of each command sent to them as SSM does, and
+// reports each invocation as Pending and InProgress for a number of polls before it completes with a programmed status
+// and output, eg.
+//
+//	client := testerfake.NewClient(
+//		testerfake.Instance{InstanceId: "i-1", Tags: map[string]string{"Name": "app"}},
+//		testerfake.Instance{InstanceId: "i-2", Tags: map[string]string{"Name": "app"}},
+//	).Respond(testerfake.OnInstance("i-2", testerfake.CommandContaining("/dev/tcp/db/5432", testerfake.Response{
+//		Status: types.CommandInvocationStatusFailed,
+//		Output: "FAIL: cannot connect",
+//		Polls:  2,
+//	})))
+//	passed, err := tester.RunTestCaseForTargetE(t, client, testCase, tester.NewTagNameTarget("app"), retryConfig)
+package testerfake
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxOutputLength is the number of characters of output SSM returns for each command plugin.
+const maxOutputLength = 2500
+
+// errorSeparator separates the standard output from the standard error in the output of a command plugin, as by SSM.
+const errorSeparator = "\n----------ERROR-------\n"
+
+// defaultPageSize is the number of invocations returned by ListCommandInvocations if MaxResults is not set.
+const defaultPageSize = 50
+
+// Instance is an instance of the simulated fleet.
+type Instance struct {
+	InstanceId string
+	Tags       map[string]string
+}
+
+// Invocation is a command sent to an instance, passed to a Responder to decide its outcome.
+type Invocation struct {
+	CommandId    string
+	Instance     Instance
+	DocumentName string
+	Parameters   map[string][]string
+}
+
+// Response is the programmed outcome of an Invocation.
+type Response struct {
+	Status       types.CommandInvocationStatus // the status the invocation completes with, Success if empty
+	Output       string                        // the output of the command, truncated to 2500 characters as by SSM
+	ResponseCode int32                         // the exit code of the command, 0 for Success and 1 otherwise if not set
+	Polls        int                           // the number of polls the invocation is Pending then InProgress before it completes
+}
+
+// Responder returns the Response to an Invocation, and true, or false if it does not respond to the Invocation.
+type Responder func(invocation Invocation) (Response, bool)
+
+// CommandContaining returns a Responder that responds with response to invocations whose commands contain substring.
+func CommandContaining(substring string, response Response) Responder {
+	return func(invocation Invocation) (Response, bool) {
+		return response, strings.Contains(strings.Join(invocation.Parameters["commands"], "\n"), substring)
+	}
+}
+
+// OnInstance returns a Responder that responds as responder only to the invocations on the instance with instanceId.
+func OnInstance(instanceId string, responder Responder) Responder {
+	return func(invocation Invocation) (Response, bool) {
+		if invocation.Instance.InstanceId != instanceId {
+			return Response{}, false
+		}
+		return responder(invocation)
+	}
+}
+
+// Always returns a Responder that responds with response to every invocation.
+func Always(response Response) Responder {
+	return func(invocation Invocation) (Response, bool) {
+		return response, true
+	}
+}
+
+// Client is a fake of the SendCommand and ListCommandInvocations operations of the AWS SSM API. It may be used wherever
+// the tester package expects an SSM client. It is safe for concurrent use.
+type Client struct {
+	mutex       sync.Mutex
+	instances   []Instance
+	responders  []Responder
+	sendErr     error
+	commands    []*ssm.SendCommandInput
+	invocations map[string][]*invocationState // the invocations of each command, by command id
+	lastCommand int
+}
+
+type invocationState struct {
+	invocation Invocation
+	response   Response
+	polls      int // the number of times the invocation has been listed
+	requested  time.Time
+}
+
+// NewClient is a constructor for Client type.
+// instances are the simulated fleet the commands sent are matched against. By default every invocation succeeds on the
+// first poll, with no output.
+func NewClient(instances ...Instance) *Client {
+	return &Client{
+		instances:   instances,
+		invocations: map[string][]*invocationState{},
+	}
+}
+
+// Respond adds responder to the Client, and returns the Client. Responders are asked in the order they were added, and
+// the first to respond decides the outcome of an invocation. Invocations no responder responds to succeed.
+func (c *Client) Respond(responder Responder) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.responders = append(c.responders, responder)
+	return c
+}
+
+// FailSendCommand makes SendCommand return err, or succeed again if err is nil, and returns the Client.
+func (c *Client) FailSendCommand(err error) *Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sendErr = err
+	return c
+}
+
+// Commands returns the input of each SendCommand call, in the order they were sent.
+func (c *Client) Commands() []*ssm.SendCommandInput {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]*ssm.SendCommandInput{}, c.commands...)
+}
+
+// SendCommand records the command, and creates an invocation of it for each instance of the fleet that matches its
+// InstanceIds or Targets. Targets are matched as by SSM: an instance must match every target, by InstanceIds, tag:<key>
+// or tag-key. Other target keys return an InvalidTarget error.
+func (c *Client) SendCommand(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.sendErr != nil {
+		return nil, c.sendErr
+	}
+	if params.DocumentName == nil {
+		return nil, &types.InvalidDocument{Message: aws.String("DocumentName is required")}
+	}
+	for _, v := range params.Targets {
+		if key := aws.ToString(v.Key); key != "InstanceIds" && key != "tag-key" && !strings.HasPrefix(key, "tag:") {
+			return nil, &types.InvalidTarget{Message: aws.String(fmt.Sprintf("target key %s is not supported by testerfake", key))}
+		}
+	}
+	c.commands = append(c.commands, params)
+	c.lastCommand++
+	commandId := fmt.Sprintf("00000000-0000-0000-0000-%012d", c.lastCommand)
+
+	// a command that matches no instances has no invocations, as by SSM
+	c.invocations[commandId] = []*invocationState{}
+	var instanceIds []string
+	for _, instance := range c.instances {
+		if !instance.Matches(params) {
+			continue
+		}
+		invocation := Invocation{
+			CommandId:    commandId,
+			Instance:     instance,
+			DocumentName: *params.DocumentName,
+			Parameters:   params.Parameters,
+		}
+		c.invocations[commandId] = append(c.invocations[commandId], &invocationState{
+			invocation: invocation,
+			response:   c.respond(invocation),
+			requested:  time.Now(),
+		})
+		instanceIds = append(instanceIds, instance.InstanceId)
+	}
+	return &ssm.SendCommandOutput{Command: &types.Command{
+		CommandId:       aws.String(commandId),
+		DocumentName:    params.DocumentName,
+		DocumentVersion: params.DocumentVersion,
+		InstanceIds:     instanceIds,
+		Parameters:      params.Parameters,
+		Targets:         params.Targets,
+		TargetCount:     int32(len(instanceIds)),
+		Status:          types.CommandStatusPending,
+	}}, nil
+}
+
+// ListCommandInvocations returns the invocations of the command, each advancing its status by one poll. The output of
+// the invocations is only returned if Details is true, and the invocations are paged by MaxResults, as by SSM.
+func (c *Client) ListCommandInvocations(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	states, found := c.invocations[aws.ToString(params.CommandId)]
+	if !found {
+		return nil, &types.InvalidCommandId{Message: aws.String(fmt.Sprintf("command %s not found", aws.ToString(params.CommandId)))}
+	}
+	start := 0
+	if params.NextToken != nil {
+		var err error
+		if start, err = strconv.Atoi(*params.NextToken); err != nil || start < 0 || start > len(states) {
+			return nil, &types.InvalidNextToken{Message: aws.String("invalid next token")}
+		}
+	}
+	pageSize := defaultPageSize
+	if params.MaxResults != 0 {
+		pageSize = int(params.MaxResults)
+	}
+	end := start + pageSize
+	if end > len(states) {
+		end = len(states)
+	}
+
+	output := &ssm.ListCommandInvocationsOutput{CommandInvocations: []types.CommandInvocation{}}
+	for _, state := range states[start:end] {
+		state.polls++
+		output.CommandInvocations = append(output.CommandInvocations, state.commandInvocation(params.Details))
+	}
+	if end < len(states) {
+		output.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return output, nil
+}
+
+// respond returns the Response of the first responder to respond to invocation.
+func (c *Client) respond(invocation Invocation) Response {
+	for _, responder := range c.responders {
+		if response, ok := responder(invocation); ok {
+			return response
+		}
+	}
+	return Response{}
+}
+
+func (is *invocationState) commandInvocation(details bool) types.CommandInvocation {
+	status, responseCode, output := is.response.Status, is.response.ResponseCode, is.response.Output
+	if status == "" {
+		status = types.CommandInvocationStatusSuccess
+	}
+	if responseCode == 0 && status != types.CommandInvocationStatusSuccess {
+		responseCode = 1
+	}
+	switch {
+	case is.polls <= 1 && is.polls <= is.response.Polls:
+		status, responseCode, output = types.CommandInvocationStatusPending, -1, ""
+	case is.polls <= is.response.Polls:
+		status, responseCode, output = types.CommandInvocationStatusInProgress, -1, ""
+	}
+	output = PluginOutput(output, "")
+	requested := is.requested
+	invocation := types.CommandInvocation{
+		CommandId:         aws.String(is.invocation.CommandId),
+		InstanceId:        aws.String(is.invocation.Instance.InstanceId),
+		InstanceName:      aws.String(is.invocation.Instance.Tags["Name"]),
+		DocumentName:      aws.String(is.invocation.DocumentName),
+		RequestedDateTime: &requested,
+		Status:            status,
+		StatusDetails:     aws.String(string(status)),
+	}
+	if details {
+		invocation.CommandPlugins = []types.CommandPlugin{{
+			Name:          aws.String(pluginName(is.invocation.DocumentName)),
+			Output:        aws.String(output),
+			ResponseCode:  responseCode,
+			Status:        types.CommandPluginStatus(status),
+			StatusDetails: aws.String(string(status)),
+		}}
+	}
+	return invocation
+}
+
+// PluginOutput returns the output of a command plugin as SSM reports it for a script that wrote stdout to its standard
+// output and stderr to its standard error. The standard error follows the standard output after a separator, and the
+// output is cut to its first 2500 bytes, without splitting a multi-byte character.
+func PluginOutput(stdout, stderr string) string {
+	output := stdout
+	if stderr != "" {
+		output += errorSeparator + stderr
+	}
+	if len(output) <= maxOutputLength {
+		return output
+	}
+	end := maxOutputLength
+	for end > 0 && !utf8.RuneStart(output[end]) {
+		end--
+	}
+	return output[:end]
+}
+
+// pluginName returns the name of the plugin of the document, as reported by SSM for the documents used by the tester.
+func pluginName(documentName string) string {
+	if documentName == "AWS-RunPowerShellScript" {
+		return "aws:runPowerShellScript"
+	}
+	return "aws:runShellScript"
+}
+
+// Matches returns true if the instance is targeted by the InstanceIds and each of the Targets of the command, by
+// InstanceIds, tag:<key> or tag-key. Other target keys do not match.
+func (instance Instance) Matches(params *ssm.SendCommandInput) bool {
+	if len(params.InstanceIds) == 0 && len(params.Targets) == 0 {
+		return false
+	}
+	if len(params.InstanceIds) > 0 && !contains(params.InstanceIds, instance.InstanceId) {
+		return false
+	}
+	for _, v := range params.Targets {
+		key := aws.ToString(v.Key)
+		switch {
+		case key == "InstanceIds":
+			if !contains(v.Values, instance.InstanceId) {
+				return false
+			}
+		case key == "tag-key":
+			found := false
+			for _, tagKey := range v.Values {
+				_, hasTag := instance.Tags[tagKey]
+				found = found || hasTag
+			}
+			if !found {
+				return false
+			}
+		case strings.HasPrefix(key, "tag:"):
+			value, hasTag := instance.Tags[strings.TrimPrefix(key, "tag:")]
+			if !hasTag || !contains(v.Values, value) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package testerfake_test
+
+import (
+	"context"
+	"github.com/ankitwal/ssm-tester/tester"
+	"github.com/ankitwal/ssm-tester/tester/testerfake"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFleet() *testerfake.Client {
+	return testerfake.NewClient(
+		testerfake.Instance{InstanceId: "i-1", Tags: map[string]string{"Name": "app", "env": "prod"}},
+		testerfake.Instance{InstanceId: "i-2", Tags: map[string]string{"Name": "app", "env": "test"}},
+		testerfake.Instance{InstanceId: "i-3", Tags: map[string]string{"Name": "db"}},
+	)
+}
+
+func TestClientTargets(t *testing.T) {
+	cases := []struct {
+		caseName            string
+		input               *ssm.SendCommandInput
+		expectedInstanceIds []string
+	}{
+		{"tag", &ssm.SendCommandInput{Targets: []types.Target{{Key: aws.String("tag:Name"), Values: []string{"app"}}}}, []string{"i-1", "i-2"}},
+		{"all the targets", &ssm.SendCommandInput{Targets: []types.Target{
+			{Key: aws.String("tag:Name"), Values: []string{"app"}},
+			{Key: aws.String("tag:env"), Values: []string{"prod"}},
+		}}, []string{"i-1"}},
+		{"tag key", &ssm.SendCommandInput{Targets: []types.Target{{Key: aws.String("tag-key"), Values: []string{"env"}}}}, []string{"i-1", "i-2"}},
+		{"target instance ids", &ssm.SendCommandInput{Targets: []types.Target{{Key: aws.String("InstanceIds"), Values: []string{"i-3"}}}}, []string{"i-3"}},
+		{"instance ids", &ssm.SendCommandInput{InstanceIds: []string{"i-2", "i-3"}}, []string{"i-2", "i-3"}},
+		{"no match", &ssm.SendCommandInput{Targets: []types.Target{{Key: aws.String("tag:Name"), Values: []string{"web"}}}}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			c.input.DocumentName = aws.String("AWS-RunShellScript")
+			output, err := newFleet().SendCommand(context.Background(), c.input)
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			if e, a := c.expectedInstanceIds, output.Command.InstanceIds; !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected instances %v, but got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestClientUnsupportedTarget(t *testing.T) {
+	_, err := newFleet().SendCommand(context.Background(), &ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunShellScript"),
+		Targets:      []types.Target{{Key: aws.String("resource-groups:Name"), Values: []string{"app"}}},
+	})
+	if _, isInvalidTarget := err.(*types.InvalidTarget); !isInvalidTarget {
+		t.Errorf("Expected an InvalidTarget error, but got %v", err)
+	}
+}
+
+func TestClientNoMatchingInstances(t *testing.T) {
+	client := newFleet()
+	output, err := client.SendCommand(context.Background(), &ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunShellScript"),
+		Targets:      []types.Target{{Key: aws.String("tag:Name"), Values: []string{"web"}}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	listOutput, err := client.ListCommandInvocations(context.Background(), &ssm.ListCommandInvocationsInput{CommandId: output.Command.CommandId})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if e, a := []types.CommandInvocation{}, listOutput.CommandInvocations; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected no invocations, but got %v", a)
+	}
+	_, err = tester.RunTestCaseForTargetE(t, client, tester.NewShellTestCase("true", true), tester.NewTagNameTarget("web"),
+		tester.NewRetryConfig(1, 0))
+	if err == nil || !strings.Contains(err.Error(), "no invocations found") {
+		t.Errorf("Expected no invocations to be found, but got %v", err)
+	}
+}
+
+func TestClientOutputTruncation(t *testing.T) {
+	// the 2500th byte is the first byte of a two byte character, which is not split
+	client := newFleet().Respond(testerfake.Always(testerfake.Response{Output: "a" + strings.Repeat("é", 1300)}))
+	output, err := client.SendCommand(context.Background(), &ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunShellScript"),
+		InstanceIds:  []string{"i-1"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	listOutput, err := client.ListCommandInvocations(context.Background(), &ssm.ListCommandInvocationsInput{
+		CommandId: output.Command.CommandId,
+		Details:   true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if e, a := "a"+strings.Repeat("é", 1249), *listOutput.CommandInvocations[0].CommandPlugins[0].Output; e != a {
+		t.Errorf("Expected the output to be truncated to %d bytes, but got %d bytes", len(e), len(a))
+	}
+}
+
+func TestPluginOutput(t *testing.T) {
+	cases := []struct {
+		caseName       string
+		stdout         string
+		stderr         string
+		expectedOutput string
+	}{
+		{"standard output only", "ok\n", "", "ok\n"},
+		{"standard error", "ok\n", "oops\n", "ok\n\n----------ERROR-------\noops\n"},
+		{"truncated standard error", strings.Repeat("a", 2490), "oops\n", strings.Repeat("a", 2490) + "\n---------"},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if e, a := c.expectedOutput, testerfake.PluginOutput(c.stdout, c.stderr); e != a {
+				t.Errorf("Expected the output to be %q, but got %q", e, a)
+			}
+		})
+	}
+}
+
+func TestClientStatusProgression(t *testing.T) {
+	client := newFleet().Respond(testerfake.Always(testerfake.Response{
+		Status: types.CommandInvocationStatusFailed,
+		Output: "FAIL: lol",
+		Polls:  2,
+	}))
+	output, err := client.SendCommand(context.Background(), &ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunShellScript"),
+		InstanceIds:  []string{"i-1"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	var statuses []types.CommandInvocationStatus
+	var outputs []string
+	for i := 0; i < 4; i++ {
+		listOutput, err := client.ListCommandInvocations(context.Background(), &ssm.ListCommandInvocationsInput{
+			CommandId: output.Command.CommandId,
+			Details:   true,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		statuses = append(statuses, listOutput.CommandInvocations[0].Status)
+		outputs = append(outputs, *listOutput.CommandInvocations[0].CommandPlugins[0].Output)
+	}
+	expectedStatuses := []types.CommandInvocationStatus{
+		types.CommandInvocationStatusPending,
+		types.CommandInvocationStatusInProgress,
+		types.CommandInvocationStatusFailed,
+		types.CommandInvocationStatusFailed,
+	}
+	if e, a := expectedStatuses, statuses; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected statuses %v, but got %v", e, a)
+	}
+	if e, a := []string{"", "", "FAIL: lol", "FAIL: lol"}, outputs; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected outputs %v, but got %v", e, a)
+	}
+}
+
+func TestClientPagination(t *testing.T) {
+	client := newFleet()
+	output, _ := client.SendCommand(context.Background(), &ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunShellScript"),
+		InstanceIds:  []string{"i-1", "i-2", "i-3"},
+	})
+	var instanceIds []string
+	var nextToken *string
+	for {
+		listOutput, err := client.ListCommandInvocations(context.Background(), &ssm.ListCommandInvocationsInput{
+			CommandId:  output.Command.CommandId,
+			MaxResults: 2,
+			NextToken:  nextToken,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		for _, v := range listOutput.CommandInvocations {
+			instanceIds = append(instanceIds, *v.InstanceId)
+			if v.CommandPlugins != nil {
+				t.Errorf("Expected no output without details, but got %v", v.CommandPlugins)
+			}
+		}
+		if nextToken = listOutput.NextToken; nextToken == nil {
+			break
+		}
+	}
+	if e, a := []string{"i-1", "i-2", "i-3"}, instanceIds; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected instances %v, but got %v", e, a)
+	}
+}
+
+func TestClientWithTester(t *testing.T) {
+	client := newFleet().Respond(testerfake.OnInstance("i-2", testerfake.CommandContaining("/dev/tcp/db/5432", testerfake.Response{
+		Status: types.CommandInvocationStatusFailed,
+		Output: "FAIL: cannot connect",
+		Polls:  1,
+	})))
+	testCase := tester.NewShellTestCase("timeout 3 bash -c '</dev/tcp/db/5432'", true)
+	result, err := tester.RunTestCaseForTargetWithResultsE(t, client, testCase, tester.NewTagNameTarget("app"),
+		tester.NewRetryConfig(3, time.Nanosecond))
+	if err == nil {
+		t.Errorf("Expected an error, but got nil")
+	}
+	expected := map[string]types.CommandInvocationStatus{"i-1": types.CommandInvocationStatusSuccess, "i-2": types.CommandInvocationStatusFailed}
+	statuses := map[string]types.CommandInvocationStatus{}
+	for _, v := range result.Invocations {
+		statuses[v.InstanceId] = v.Status
+	}
+	if e, a := expected, statuses; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected statuses %v, but got %v", e, a)
+	}
+	if e, a := 1, len(client.Commands()); e != a {
+		t.Errorf("Expected %d commands sent, but got %d", e, a)
+	}
+
+	passed, err := tester.RunTestCaseForTargetE(t, client, tester.NewShellTestCase("true", true), tester.NewTagNameTarget("db"),
+		tester.NewRetryConfig(0, 0))
+	if !passed || err != nil {
+		t.Errorf("Expected the test to pass, but got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+// Package testerlocal runs the commands of the tester package on local docker containers or local processes instead of
+// EC2 instances, so that test cases can be developed and debugged on a laptop, or run in CI without AWS credentials.
+//
+// A Client implements the SendCommand and ListCommandInvocations operations of the AWS SSM API for the
+// AWS-RunShellScript document. Targets are matched against the labels of running containers as if they were the tags of
+// instances, eg. tester.NewTagNameTarget("app") targets the containers started with --label Name=app, and the name of a
+// container is its instance id. Each invocation is Pending until it starts, InProgress while it runs, and then Success
+// or Failed by the exit code of the script, or TimedOut after its executionTimeout, as on an instance. A script that
+// times out is killed with the processes it started.
+//
+//	client := testerlocal.NewDockerClient("docker")
+//	tester.RunTestCaseForTarget(t, client, testCase, tester.NewTagNameTarget("app"), retryConfig)
+package testerlocal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ankitwal/ssm-tester/tester/testerfake"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	runShellScript = "AWS-RunShellScript"
+	// defaultExecutionTimeout is the executionTimeout of AWS-RunShellScript, in seconds.
+	defaultExecutionTimeout = 3600
+)
+
+// localInstance is a container or local process environment that commands can be run on.
+type localInstance struct {
+	testerfake.Instance
+	command func(script string, timeout int) *exec.Cmd // the command that runs script on the instance, for timeout seconds
+}
+
+// Client runs commands sent with SendCommand on local docker containers or processes. It is safe for concurrent use.
+type Client struct {
+	fleet func(ctx context.Context) ([]localInstance, error) // the instances commands can be sent to
+
+	mutex       sync.Mutex
+	invocations map[string][]*invocation // the invocations of each command, by command id
+	lastCommand int
+}
+
+type invocation struct {
+	instanceId   string
+	instanceName string
+	documentName string
+	requested    time.Time
+	status       types.CommandInvocationStatus
+	exitCode     int32
+	stdout       string
+	stderr       string
+}
+
+// NewDockerClient is a constructor for a Client that runs commands on the running containers of the docker daemon, with
+// dockerCommand exec <container> timeout <executionTimeout> sh -c <script>. dockerCommand is the docker command line
+// tool, eg. "docker", or another with the same commands, eg. "podman". The containers must have the timeout command,
+// as in coreutils or busybox images.
+//
+// The running containers are listed for each command sent, with their labels as tags and their names as instance ids.
+func NewDockerClient(dockerCommand string) *Client {
+	return &Client{
+		fleet: func(ctx context.Context) ([]localInstance, error) {
+			return dockerInstances(ctx, dockerCommand)
+		},
+		invocations: map[string][]*invocation{},
+	}
+}
+
+// NewProcessClient is a constructor for a Client that runs commands as local processes, with sh -c <script>, as if on
+// each of the instances. Each process has the environment of the current process, and SSM_TESTER_INSTANCE_ID set to the
+// id of its instance.
+func NewProcessClient(instances ...testerfake.Instance) *Client {
+	var fleet []localInstance
+	for _, v := range instances {
+		instanceId := v.InstanceId
+		fleet = append(fleet, localInstance{
+			Instance: v,
+			command: func(script string, timeout int) *exec.Cmd {
+				cmd := exec.Command("sh", "-c", script)
+				cmd.Env = append(os.Environ(), "SSM_TESTER_INSTANCE_ID="+instanceId)
+				return cmd
+			},
+		})
+	}
+	return &Client{
+		fleet: func(ctx context.Context) ([]localInstance, error) {
+			return fleet, nil
+		},
+		invocations: map[string][]*invocation{},
+	}
+}
+
+// dockerContainer are the fields of docker inspect used to build the fleet.
+type dockerContainer struct {
+	Id     string
+	Name   string
+	Config struct {
+		Labels map[string]string
+	}
+}
+
+func dockerInstances(ctx context.Context, dockerCommand string) ([]localInstance, error) {
+	ids, err := exec.CommandContext(ctx, dockerCommand, "ps", "-q").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+	if len(strings.Fields(string(ids))) == 0 {
+		return nil, nil
+	}
+	inspected, err := exec.CommandContext(ctx, dockerCommand, append([]string{"inspect"}, strings.Fields(string(ids))...)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("inspecting containers: %w", err)
+	}
+	var containers []dockerContainer
+	if err := json.Unmarshal(inspected, &containers); err != nil {
+		return nil, fmt.Errorf("inspecting containers: %w", err)
+	}
+	var fleet []localInstance
+	for _, v := range containers {
+		name := strings.TrimPrefix(v.Name, "/")
+		fleet = append(fleet, localInstance{
+			Instance: testerfake.Instance{InstanceId: name, Tags: v.Config.Labels},
+			command: func(script string, timeout int) *exec.Cmd {
+				// killing docker exec does not stop the script in the container, so it is timed out there too
+				return exec.Command(dockerCommand, "exec", name, "timeout", strconv.Itoa(timeout), "sh", "-c", script)
+			},
+		})
+	}
+	return fleet, nil
+}
+
+// SendCommand starts the script of the AWS-RunShellScript command on each instance that matches its InstanceIds or
+// Targets, and returns without waiting for them to complete.
+func (c *Client) SendCommand(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+	if documentName := aws.ToString(params.DocumentName); documentName != runShellScript {
+		return nil, &types.InvalidDocument{Message: aws.String(fmt.Sprintf("document %s is not supported by testerlocal", documentName))}
+	}
+	timeout := defaultExecutionTimeout
+	if v := params.Parameters["executionTimeout"]; len(v) > 0 {
+		var err error
+		if timeout, err = strconv.Atoi(v[0]); err != nil {
+			return nil, &types.InvalidParameters{Message: aws.String(fmt.Sprintf("executionTimeout %s is not a number", v[0]))}
+		}
+	}
+	fleet, err := c.fleet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	script := strings.Join(params.Parameters["commands"], "\n")
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.lastCommand++
+	commandId := fmt.Sprintf("local-%d-%d", time.Now().Unix(), c.lastCommand)
+	c.invocations[commandId] = []*invocation{}
+	var instanceIds []string
+	for _, v := range fleet {
+		if !v.Matches(params) {
+			continue
+		}
+		invocation := &invocation{
+			instanceId:   v.InstanceId,
+			instanceName: v.Tags["Name"],
+			documentName: runShellScript,
+			requested:    time.Now(),
+			status:       types.CommandInvocationStatusPending,
+			exitCode:     -1,
+		}
+		c.invocations[commandId] = append(c.invocations[commandId], invocation)
+		instanceIds = append(instanceIds, v.InstanceId)
+		go c.run(v, invocation, script, timeout)
+	}
+	return &ssm.SendCommandOutput{Command: &types.Command{
+		CommandId:    aws.String(commandId),
+		DocumentName: params.DocumentName,
+		InstanceIds:  instanceIds,
+		Parameters:   params.Parameters,
+		Targets:      params.Targets,
+		TargetCount:  int32(len(instanceIds)),
+		Status:       types.CommandStatusPending,
+	}}, nil
+}
+
+// run runs the script on the instance for at most timeout seconds, and updates the status of the invocation as it starts
+// and completes.
+func (c *Client) run(instance localInstance, invocation *invocation, script string, timeout int) {
+	var stdout, stderr bytes.Buffer
+	cmd := instance.command(script, timeout)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	startProcessGroup(cmd)
+
+	c.mutex.Lock()
+	invocation.status = types.CommandInvocationStatusInProgress
+	c.mutex.Unlock()
+	started := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		timer := time.AfterFunc(time.Duration(timeout)*time.Second, func() { killProcessGroup(cmd) })
+		err = cmd.Wait()
+		timer.Stop()
+	}
+	timedOut := time.Since(started) >= time.Duration(timeout)*time.Second
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	invocation.stdout, invocation.stderr = stdout.String(), stderr.String()
+	invocation.status, invocation.exitCode = types.CommandInvocationStatusSuccess, 0
+	switch exitErr, isExitErr := err.(*exec.ExitError); {
+	case timedOut:
+		invocation.status, invocation.exitCode = types.CommandInvocationStatusTimedOut, -1
+	case isExitErr:
+		invocation.status, invocation.exitCode = types.CommandInvocationStatusFailed, int32(exitErr.ExitCode())
+	case err != nil:
+		// the script could not be started eg. the container has stopped
+		invocation.status, invocation.exitCode = types.CommandInvocationStatusFailed, 1
+		invocation.stderr += err.Error()
+	}
+}
+
+// ListCommandInvocations returns the current state of each invocation of the command. The output of the invocations is
+// only returned if Details is true, as by SSM. All the invocations are returned in a single page.
+func (c *Client) ListCommandInvocations(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	invocations, found := c.invocations[aws.ToString(params.CommandId)]
+	if !found {
+		return nil, &types.InvalidCommandId{Message: aws.String(fmt.Sprintf("command %s not found", aws.ToString(params.CommandId)))}
+	}
+	output := &ssm.ListCommandInvocationsOutput{CommandInvocations: []types.CommandInvocation{}}
+	for _, v := range invocations {
+		output.CommandInvocations = append(output.CommandInvocations, v.commandInvocation(aws.ToString(params.CommandId), params.Details))
+	}
+	return output, nil
+}
+
+func (i *invocation) commandInvocation(commandId string, details bool) types.CommandInvocation {
+	requested := i.requested
+	commandInvocation := types.CommandInvocation{
+		CommandId:         aws.String(commandId),
+		InstanceId:        aws.String(i.instanceId),
+		InstanceName:      aws.String(i.instanceName),
+		DocumentName:      aws.String(i.documentName),
+		RequestedDateTime: &requested,
+		Status:            i.status,
+		StatusDetails:     aws.String(string(i.status)),
+	}
+	if details {
+		commandInvocation.CommandPlugins = []types.CommandPlugin{{
+			Name:          aws.String("aws:runShellScript"),
+			Output:        aws.String(testerfake.PluginOutput(i.stdout, i.stderr)),
+			ResponseCode:  i.exitCode,
+			Status:        types.CommandPluginStatus(i.status),
+			StatusDetails: aws.String(string(i.status)),
+		}}
+	}
+	return commandInvocation
+}
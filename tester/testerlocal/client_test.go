@@ -0,0 +1,203 @@
+package testerlocal_test
+
+import (
+	"context"
+	"github.com/ankitwal/ssm-tester/tester"
+	"github.com/ankitwal/ssm-tester/tester/testerfake"
+	"github.com/ankitwal/ssm-tester/tester/testerlocal"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForInvocations sends the commands to the instances of client, and returns the invocations once they are complete.
+func waitForInvocations(t *testing.T, client *testerlocal.Client, input *ssm.SendCommandInput) []types.CommandInvocation {
+	input.DocumentName = aws.String("AWS-RunShellScript")
+	output, err := client.SendCommand(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+		listOutput, err := client.ListCommandInvocations(context.Background(), &ssm.ListCommandInvocationsInput{
+			CommandId: output.Command.CommandId,
+			Details:   true,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		complete := true
+		for _, v := range listOutput.CommandInvocations {
+			switch v.Status {
+			case types.CommandInvocationStatusPending, types.CommandInvocationStatusInProgress:
+				complete = false
+			}
+		}
+		if complete {
+			return listOutput.CommandInvocations
+		}
+	}
+	t.Fatalf("Expected the invocations to complete")
+	return nil
+}
+
+func TestProcessClient(t *testing.T) {
+	client := testerlocal.NewProcessClient(
+		testerfake.Instance{InstanceId: "i-1", Tags: map[string]string{"Name": "app"}},
+		testerfake.Instance{InstanceId: "i-2", Tags: map[string]string{"Name": "app"}},
+		testerfake.Instance{InstanceId: "i-3", Tags: map[string]string{"Name": "db"}},
+	)
+	// fails on i-2 only
+	invocations := waitForInvocations(t, client, &ssm.SendCommandInput{
+		Targets: []types.Target{{Key: aws.String("tag:Name"), Values: []string{"app"}}},
+		Parameters: map[string][]string{"commands": {
+			`echo "instance=$SSM_TESTER_INSTANCE_ID"`,
+			`[ "$SSM_TESTER_INSTANCE_ID" != i-2 ] || { echo oops >&2; exit 3; }`,
+		}},
+	})
+	type outcome struct {
+		instanceId   string
+		status       types.CommandInvocationStatus
+		responseCode int32
+		output       string
+	}
+	var outcomes []outcome
+	for _, v := range invocations {
+		outcomes = append(outcomes, outcome{*v.InstanceId, v.Status, v.CommandPlugins[0].ResponseCode, *v.CommandPlugins[0].Output})
+	}
+	expected := []outcome{
+		{"i-1", types.CommandInvocationStatusSuccess, 0, "instance=i-1\n"},
+		{"i-2", types.CommandInvocationStatusFailed, 3, "instance=i-2\n\n----------ERROR-------\noops\n"},
+	}
+	if e, a := expected, outcomes; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the invocations to be \n%v, but got \n%v", e, a)
+	}
+}
+
+func TestProcessClientOutputTruncation(t *testing.T) {
+	client := testerlocal.NewProcessClient(testerfake.Instance{InstanceId: "i-1"})
+	// the 2500th byte is the first byte of a two byte character, which is not split
+	invocations := waitForInvocations(t, client, &ssm.SendCommandInput{
+		InstanceIds: []string{"i-1"},
+		Parameters: map[string][]string{"commands": {
+			`printf a; i=0; while [ $i -lt 1300 ]; do printf 'é'; i=$((i + 1)); done`,
+		}},
+	})
+	if e, a := "a"+strings.Repeat("é", 1249), *invocations[0].CommandPlugins[0].Output; e != a {
+		t.Errorf("Expected the output to be truncated to %d bytes, but got %d bytes", len(e), len(a))
+	}
+}
+
+func TestProcessClientWithTester(t *testing.T) {
+	client := testerlocal.NewProcessClient(
+		testerfake.Instance{InstanceId: "i-1", Tags: map[string]string{"Name": "app"}},
+		testerfake.Instance{InstanceId: "i-2", Tags: map[string]string{"Name": "app"}},
+	)
+	retryConfig := tester.NewRetryConfig(50, 100*time.Millisecond)
+	passed, err := tester.RunTestCaseForTargetE(t, client, tester.NewShellTestCase(`test "$SSM_TESTER_INSTANCE_ID" != i-2`, true),
+		tester.NewTagNameTarget("app"), retryConfig)
+	if passed || err == nil {
+		t.Errorf("Expected the test to fail on i-2, but got %v %v", passed, err)
+	}
+	passed, err = tester.RunTestCaseForTargetE(t, client, tester.NewShellTestCase(`test -n "$SSM_TESTER_INSTANCE_ID"`, true),
+		tester.NewTagNameTarget("app"), retryConfig)
+	if !passed || err != nil {
+		t.Errorf("Expected the test to pass, but got %v", err)
+	}
+}
+
+func TestProcessClientLifecycle(t *testing.T) {
+	client := testerlocal.NewProcessClient(testerfake.Instance{InstanceId: "i-1"})
+	output, err := client.SendCommand(context.Background(), &ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunShellScript"),
+		InstanceIds:  []string{"i-1"},
+		Parameters:   map[string][]string{"commands": {"sleep 5"}, "executionTimeout": {"1"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	seen := map[types.CommandInvocationStatus]bool{}
+	for start := time.Now(); time.Since(start) < 10*time.Second && !seen[types.CommandInvocationStatusTimedOut]; time.Sleep(10 * time.Millisecond) {
+		listOutput, err := client.ListCommandInvocations(context.Background(), &ssm.ListCommandInvocationsInput{CommandId: output.Command.CommandId})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		seen[listOutput.CommandInvocations[0].Status] = true
+	}
+	if !seen[types.CommandInvocationStatusInProgress] || !seen[types.CommandInvocationStatusTimedOut] {
+		t.Errorf("Expected the invocation to be InProgress and then TimedOut, but got %v", seen)
+	}
+}
+
+func TestProcessClientTimeoutKillsScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testerlocal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	marker := filepath.Join(dir, "marker")
+	// the background subshell outlives sh unless its process group is killed
+	invocations := waitForInvocations(t, testerlocal.NewProcessClient(testerfake.Instance{InstanceId: "i-1"}), &ssm.SendCommandInput{
+		InstanceIds: []string{"i-1"},
+		Parameters: map[string][]string{
+			"commands":         {"(sleep 2; touch '" + marker + "') &", "wait"},
+			"executionTimeout": {"1"},
+		},
+	})
+	if e, a := types.CommandInvocationStatusTimedOut, invocations[0].Status; e != a {
+		t.Fatalf("Expected the invocation to be %s, but got %s", e, a)
+	}
+	time.Sleep(2 * time.Second)
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("Expected the script to be killed when it timed out, but it created %s", marker)
+	}
+}
+
+func TestUnsupportedDocument(t *testing.T) {
+	_, err := testerlocal.NewProcessClient().SendCommand(context.Background(), &ssm.SendCommandInput{
+		DocumentName: aws.String("AWS-RunPowerShellScript"),
+	})
+	if _, isInvalidDocument := err.(*types.InvalidDocument); !isInvalidDocument {
+		t.Errorf("Expected an InvalidDocument error, but got %v", err)
+	}
+}
+
+// fakeDocker lists two containers labelled Name=web and Name=db, and runs exec locally.
+const fakeDocker = `#!/bin/sh
+case "$1" in
+ps) echo aaa; echo bbb ;;
+inspect) echo '[{"Id": "aaa", "Name": "/web-1", "Config": {"Labels": {"Name": "web"}}}, {"Id": "bbb", "Name": "/db-1", "Config": {"Labels": {"Name": "db"}}}]' ;;
+exec) shift; echo "container=$1"; shift; echo "run=$1 $2"; exec "$@" ;;
+esac
+`
+
+func TestDockerClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testerlocal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	docker := filepath.Join(dir, "docker")
+	if err := ioutil.WriteFile(docker, []byte(fakeDocker), 0755); err != nil {
+		t.Fatal(err)
+	}
+	invocations := waitForInvocations(t, testerlocal.NewDockerClient(docker), &ssm.SendCommandInput{
+		Targets:    []types.Target{{Key: aws.String("tag:Name"), Values: []string{"db"}}},
+		Parameters: map[string][]string{"commands": {"echo hello"}},
+	})
+	if e, a := 1, len(invocations); e != a {
+		t.Fatalf("Expected %d invocations, but got %d", e, a)
+	}
+	if e, a := "db-1", *invocations[0].InstanceId; e != a {
+		t.Errorf("Expected instance %s, but got %s", e, a)
+	}
+	if e, a := "container=db-1\nrun=timeout 3600\nhello\n", *invocations[0].CommandPlugins[0].Output; e != a {
+		t.Errorf("Expected the output to be %q, but got %q", e, a)
+	}
+}
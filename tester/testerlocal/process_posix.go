@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package testerlocal
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startProcessGroup makes cmd the leader of a new process group, so that killProcessGroup also kills the processes
+// started by its script.
+func startProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills cmd and the processes started by its script.
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,13 @@
+package testerlocal
+
+import (
+	"os/exec"
+)
+
+// startProcessGroup does nothing on windows, where only cmd is killed by killProcessGroup.
+func startProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills cmd. The processes started by its script are not killed.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
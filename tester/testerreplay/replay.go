@@ -0,0 +1,238 @@
+// Package testerreplay records the interactions of the tester package with the AWS SSM API to golden files, and replays
+// them without AWS, so that a real run against an environment can be snapshotted once, and the logic of suites and
+// reporters built on it regression tested offline and fast.
+//
+// A Recorder wraps an SSM client, and records each SendCommand and ListCommandInvocations request with its response, eg.
+//
+//	recorder := testerreplay.NewRecorder(ssm.NewFromConfig(cfg))
+//	tester.RunSuite(t, recorder, suite)
+//	err := recorder.Save("testdata/staging.json")
+//
+// A Client loaded from the golden file then serves the recorded responses back, eg.
+//
+//	client, err := testerreplay.Load("testdata/staging.json")
+//	tester.RunSuite(t, client, suite.WithoutWait())
+//
+// The tester still polls a Client for the results of each command as many times as were recorded, so replayed runs
+// should not wait between polls: replay suites with Suite.WithoutWait, and test cases with a RetryConfig with the same
+// maxRetries and a waitBetweenRetries of 0.
+package testerreplay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/smithy-go"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// The operations of the SSM API that are recorded.
+const (
+	OperationSendCommand            = "SendCommand"
+	OperationListCommandInvocations = "ListCommandInvocations"
+)
+
+// ErrNotRecorded is returned by a Client for a request that was not recorded.
+var ErrNotRecorded = errors.New("request was not recorded")
+
+// commandSenderLister is the SSM client recorded by a Recorder, eg. *ssm.Client.
+type commandSenderLister interface {
+	SendCommand(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error)
+	ListCommandInvocations(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error)
+}
+
+// Interaction is a request to the SSM API and its response. Only the input and output of its Operation are set, and the
+// output is nil if the request returned an Error.
+type Interaction struct {
+	Operation                    string                            `json:"operation"`
+	SendCommandInput             *ssm.SendCommandInput             `json:"sendCommandInput,omitempty"`
+	SendCommandOutput            *ssm.SendCommandOutput            `json:"sendCommandOutput,omitempty"`
+	ListCommandInvocationsInput  *ssm.ListCommandInvocationsInput  `json:"listCommandInvocationsInput,omitempty"`
+	ListCommandInvocationsOutput *ssm.ListCommandInvocationsOutput `json:"listCommandInvocationsOutput,omitempty"`
+	Error                        *Error                            `json:"error,omitempty"`
+}
+
+// Error is an error returned by the SSM API. Code is the error code of API errors eg. InvalidCommandId, and is empty for
+// other errors eg. network errors.
+type Error struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+func newError(err error) *Error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return &Error{Code: apiErr.ErrorCode(), Message: apiErr.ErrorMessage()}
+	}
+	return &Error{Message: err.Error()}
+}
+
+// err returns the error the Error was recorded from, as a smithy.APIError if it has a Code.
+func (e *Error) err() error {
+	if e.Code != "" {
+		return &smithy.GenericAPIError{Code: e.Code, Message: e.Message}
+	}
+	return errors.New(e.Message)
+}
+
+// goldenFile is the document a Recorder saves, and Load reads.
+type goldenFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an SSM client that records the interactions of the client it wraps. It is safe for concurrent use.
+type Recorder struct {
+	client commandSenderLister
+
+	mutex        sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder is a constructor for Recorder type.
+// client is the SSM client the requests are sent to, eg. *ssm.Client.
+func NewRecorder(client commandSenderLister) *Recorder {
+	return &Recorder{client: client}
+}
+
+// SendCommand sends the command with the wrapped client, and records the request and its response.
+func (r *Recorder) SendCommand(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+	output, err := r.client.SendCommand(ctx, params, optFns...)
+	interaction := Interaction{Operation: OperationSendCommand, SendCommandInput: params, SendCommandOutput: output}
+	if err != nil {
+		interaction.SendCommandOutput, interaction.Error = nil, newError(err)
+	}
+	r.record(interaction)
+	return output, err
+}
+
+// ListCommandInvocations lists the invocations with the wrapped client, and records the request and its response.
+func (r *Recorder) ListCommandInvocations(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error) {
+	output, err := r.client.ListCommandInvocations(ctx, params, optFns...)
+	interaction := Interaction{Operation: OperationListCommandInvocations, ListCommandInvocationsInput: params, ListCommandInvocationsOutput: output}
+	if err != nil {
+		interaction.ListCommandInvocationsOutput, interaction.Error = nil, newError(err)
+	}
+	r.record(interaction)
+	return output, err
+}
+
+func (r *Recorder) record(interaction Interaction) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.interactions = append(r.interactions, interaction)
+}
+
+// Interactions returns the interactions recorded, in the order they were made.
+func (r *Recorder) Interactions() []Interaction {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Interaction{}, r.interactions...)
+}
+
+// Save writes the interactions recorded to the golden file at path, as a JSON document.
+func (r *Recorder) Save(path string) error {
+	data, err := json.MarshalIndent(goldenFile{Interactions: r.Interactions()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding interactions: %w", err)
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Client is an SSM client that replays recorded interactions. It may be used wherever the tester package expects an SSM
+// client. It is safe for concurrent use.
+//
+// Each request is answered with the response of the first interaction not yet replayed whose request is equal to it, so
+// that the same requests made in the same order get the same responses as when they were recorded. Once the interactions
+// of a ListCommandInvocations request are all replayed, the last is replayed again, as the status of a completed command
+// no longer changes. A request that was not recorded returns an error, eg. if a test case now sends a different command.
+type Client struct {
+	mutex        sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// NewClient is a constructor for Client type.
+// interactions are the interactions to replay, eg. those of Recorder.Interactions.
+func NewClient(interactions ...Interaction) *Client {
+	return &Client{
+		interactions: interactions,
+		replayed:     make([]bool, len(interactions)),
+	}
+}
+
+// Load is a constructor for a Client that replays the interactions of the golden file at path, saved by Recorder.Save.
+func Load(path string) (*Client, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var golden goldenFile
+	if err := json.Unmarshal(data, &golden); err != nil {
+		return nil, fmt.Errorf("decoding golden file %s: %w", path, err)
+	}
+	return NewClient(golden.Interactions...), nil
+}
+
+// SendCommand returns the recorded response to the command.
+func (c *Client) SendCommand(ctx context.Context, params *ssm.SendCommandInput, optFns ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+	interaction, err := c.replay(OperationSendCommand, params, func(interaction Interaction) interface{} {
+		return interaction.SendCommandInput
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: commands %s", err, strings.Join(params.Parameters["commands"], "\n"))
+	}
+	if interaction.Error != nil {
+		return nil, interaction.Error.err()
+	}
+	return interaction.SendCommandOutput, nil
+}
+
+// ListCommandInvocations returns the recorded response to the listing of the invocations.
+func (c *Client) ListCommandInvocations(ctx context.Context, params *ssm.ListCommandInvocationsInput, optFns ...func(*ssm.Options)) (*ssm.ListCommandInvocationsOutput, error) {
+	interaction, err := c.replay(OperationListCommandInvocations, params, func(interaction Interaction) interface{} {
+		return interaction.ListCommandInvocationsInput
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: command id %s", err, aws.ToString(params.CommandId))
+	}
+	if interaction.Error != nil {
+		return nil, interaction.Error.err()
+	}
+	return interaction.ListCommandInvocationsOutput, nil
+}
+
+// replay returns the first interaction of operation not yet replayed whose input, as returned by input, is equal to
+// params, or the last one replayed if they have all been replayed. Inputs are compared by their JSON encoding, as saved
+// in golden files.
+func (c *Client) replay(operation string, params interface{}, input func(Interaction) interface{}) (Interaction, error) {
+	request, err := json.Marshal(params)
+	if err != nil {
+		return Interaction{}, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	last := -1
+	for i, v := range c.interactions {
+		if v.Operation != operation {
+			continue
+		}
+		if recorded, err := json.Marshal(input(v)); err != nil || string(recorded) != string(request) {
+			continue
+		}
+		if !c.replayed[i] {
+			c.replayed[i] = true
+			return v, nil
+		}
+		last = i
+	}
+	// a command is only sent once, but its invocations are listed until they are complete
+	if last >= 0 && operation == OperationListCommandInvocations {
+		return c.interactions[last], nil
+	}
+	return Interaction{}, ErrNotRecorded
+}
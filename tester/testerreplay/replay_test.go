@@ -0,0 +1,169 @@
+package testerreplay_test
+
+import (
+	"context"
+	"errors"
+	"github.com/ankitwal/ssm-tester/tester"
+	"github.com/ankitwal/ssm-tester/tester/testerfake"
+	"github.com/ankitwal/ssm-tester/tester/testerreplay"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stripRequestedDateTime returns the result without the times the invocations were requested, which lose their
+// monotonic clock reading when saved.
+func stripRequestedDateTime(result tester.TestResult) tester.TestResult {
+	var invocations []tester.InvocationResult
+	for _, v := range result.Invocations {
+		v.RequestedDateTime = time.Time{}
+		invocations = append(invocations, v)
+	}
+	result.Invocations = invocations
+	return result
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testerreplay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "golden.json")
+
+	fake := testerfake.NewClient(
+		testerfake.Instance{InstanceId: "i-1", Tags: map[string]string{"Name": "app"}},
+		testerfake.Instance{InstanceId: "i-2", Tags: map[string]string{"Name": "app"}},
+	).Respond(testerfake.OnInstance("i-2", testerfake.Always(testerfake.Response{
+		Status: types.CommandInvocationStatusFailed,
+		Output: "FAIL: cannot connect",
+		Polls:  3,
+	})))
+	testCase := tester.NewShellTestCase("true", true)
+	target := tester.NewTagNameTarget("app")
+	retryConfig := tester.NewRetryConfig(10, 10*time.Millisecond)
+	// the recorded responses are replayed as they are requested, so there is no need to wait between polls
+	replayConfig := tester.NewRetryConfig(10, 0)
+
+	recorder := testerreplay.NewRecorder(fake)
+	recorded, err := tester.RunTestCaseForTargetWithResultsE(nil, recorder, testCase, target, retryConfig)
+	if err == nil {
+		t.Fatalf("Expected the test to fail on i-2")
+	}
+	// a SendCommand, and a ListCommandInvocations for each poll
+	if e, a := 5, len(recorder.Interactions()); e != a {
+		t.Errorf("Expected %d interactions to be recorded, but got %d", e, a)
+	}
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	client, err := testerreplay.Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	replayed, err := tester.RunTestCaseForTargetWithResultsE(nil, client, testCase, target, replayConfig)
+	if err == nil {
+		t.Errorf("Expected the replayed test to fail on i-2")
+	}
+	for i := range recorded.Invocations {
+		if e, a := recorded.Invocations[i].RequestedDateTime, replayed.Invocations[i].RequestedDateTime; !e.Equal(a) {
+			t.Errorf("Expected the invocation to be requested at %v, but got %v", e, a)
+		}
+	}
+	if e, a := stripRequestedDateTime(recorded), stripRequestedDateTime(replayed); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the replayed result to be \n%v, but got \n%v", e, a)
+	}
+
+	// the command was only sent once
+	_, err = tester.RunTestCaseForTargetWithResultsE(nil, client, testCase, target, replayConfig)
+	if !errors.Is(err, testerreplay.ErrNotRecorded) {
+		t.Errorf("Expected ErrNotRecorded, but got %v", err)
+	}
+	_, err = tester.RunTestCaseForTargetWithResultsE(nil, testerreplay.NewClient(recorder.Interactions()...),
+		tester.NewShellTestCase("false", true), target, replayConfig)
+	if !errors.Is(err, testerreplay.ErrNotRecorded) {
+		t.Errorf("Expected ErrNotRecorded, but got %v", err)
+	}
+}
+
+const replaySuite = `
+retry:
+  maxRetries: 5
+  waitBetweenRetries: 200ms
+targets:
+  app:
+    tag:
+      Name: app
+tests:
+  - name: app is up
+    target: app
+    shell:
+      command: "true"
+`
+
+func TestReplaySuiteWithoutWait(t *testing.T) {
+	suite, err := tester.ParseSuite([]byte(replaySuite))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	fake := testerfake.NewClient(testerfake.Instance{InstanceId: "i-1", Tags: map[string]string{"Name": "app"}}).
+		Respond(testerfake.Always(testerfake.Response{Polls: 2}))
+	recorder := testerreplay.NewRecorder(fake)
+	recorded, err := tester.RunSuiteE(recorder, suite)
+	if err != nil || !recorded.Passed() {
+		t.Fatalf("Expected the recorded suite to pass, but got %v", err)
+	}
+
+	start := time.Now()
+	replayed, err := tester.RunSuiteE(testerreplay.NewClient(recorder.Interactions()...), suite.WithoutWait())
+	if err != nil || !replayed.Passed() {
+		t.Fatalf("Expected the replayed suite to pass, but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+		t.Errorf("Expected the replay not to wait between polls, but it took %v", elapsed)
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	fake := testerfake.NewClient().FailSendCommand(&types.InvalidDocument{Message: aws.String("no such document")})
+	recorder := testerreplay.NewRecorder(fake)
+	sendCommandInput := &ssm.SendCommandInput{DocumentName: aws.String("AWS-RunShellScript")}
+	listCommandInput := &ssm.ListCommandInvocationsInput{CommandId: aws.String("unknown")}
+	_, sendErr := recorder.SendCommand(context.Background(), sendCommandInput)
+	_, listErr := recorder.ListCommandInvocations(context.Background(), listCommandInput)
+
+	client := testerreplay.NewClient(recorder.Interactions()...)
+	_, replayedSendErr := client.SendCommand(context.Background(), sendCommandInput)
+	_, replayedListErr := client.ListCommandInvocations(context.Background(), listCommandInput)
+
+	cases := []struct {
+		caseName string
+		recorded error
+		replayed error
+	}{
+		{"SendCommand", sendErr, replayedSendErr},
+		{"ListCommandInvocations", listErr, replayedListErr},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			var recorded, replayed smithy.APIError
+			if !errors.As(c.recorded, &recorded) || !errors.As(c.replayed, &replayed) {
+				t.Fatalf("Expected API errors, but got %v and %v", c.recorded, c.replayed)
+			}
+			if e, a := recorded.ErrorCode(), replayed.ErrorCode(); e != a {
+				t.Errorf("Expected error code %s, but got %s", e, a)
+			}
+			if e, a := recorded.ErrorMessage(), replayed.ErrorMessage(); e != a {
+				t.Errorf("Expected error message %s, but got %s", e, a)
+			}
+		})
+	}
+}
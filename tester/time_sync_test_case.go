@@ -0,0 +1,84 @@
+package tester
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimeSyncTestCase configuration for a test that checks the clock of the target instances is synchronised, and that its
+// offset from the time source is within a tolerance. Clock drift quietly breaks SigV4 request signing and Kerberos.
+// On linux the time sync daemon is detected from chrony, ntpd and systemd-timesyncd. On windows w32time is checked.
+// The offset of each instance is available from the TestResult with Offsets.
+type TimeSyncTestCase struct {
+	platformScript
+	maxOffset time.Duration // the maximum offset of the clock from the time source
+}
+
+func (tstc TimeSyncTestCase) buildCommandParameters() map[string][]string {
+	if tstc.windows {
+		return shellCommandParameters(tstc.buildPowerShellScript())
+	}
+	maxOffset := strconv.FormatFloat(tstc.maxOffset.Seconds(), 'f', -1, 64)
+	return shellCommandParameters(assertionScript(
+		`daemon=""; synchronized=no; offset=""`,
+		`if command -v chronyc >/dev/null 2>&1 && tracking=$(chronyc tracking 2>/dev/null); then`,
+		`  daemon=chrony; echo "$tracking" | grep -Eq '^Leap status *: Normal' && synchronized=yes`,
+		`  offset=$(echo "$tracking" | awk '/^System time/ { print $4 }')`,
+		`elif command -v ntpq >/dev/null 2>&1 && peers=$(ntpq -pn 2>/dev/null); then`,
+		`  daemon=ntpd; offset=$(echo "$peers" | awk '/^\*/ { o = $9 / 1000; print (o < 0 ? -o : o) }')`,
+		`  [ -n "$offset" ] && synchronized=yes`,
+		`elif command -v timedatectl >/dev/null 2>&1; then`,
+		`  daemon=timesyncd; timedatectl status 2>/dev/null | grep -q 'synchronized: yes' && synchronized=yes`,
+		`  offset=$(timedatectl timesync-status 2>/dev/null | awk '$1 == "Offset:" { v = $2; sub(/^[+-]/, "", v); if (v ~ /ms$/) { sub(/ms$/, "", v); v = v / 1000 } else if (v ~ /(us|µs)$/) { sub(/(us|µs)$/, "", v); v = v / 1000000 } else { sub(/s$/, "", v) }; print v }')`,
+		`fi`,
+		`echo "daemon=$daemon"; echo "synchronized=$synchronized"; echo "offset_seconds=$offset"`,
+		`if [ -z "$daemon" ]; then echo "FAIL: no supported time sync daemon found"; exit 1; fi`,
+		`[ "$synchronized" = yes ] || fail 'clock is not synchronized by' "$daemon"`,
+		fmt.Sprintf(`if [ -z "$offset" ]; then fail 'offset is not reported by' "$daemon"; elif ! awk -v o="$offset" 'BEGIN { exit !(o <= %s) }'; then fail 'offset is' "$offset" 'seconds expected at most %s'; fi`,
+			maxOffset, maxOffset),
+	))
+}
+
+func (tstc TimeSyncTestCase) buildPowerShellScript() []string {
+	maxOffset := strconv.FormatFloat(tstc.maxOffset.Seconds(), 'f', -1, 64)
+	return powerShellAssertionScript(
+		`$status = w32tm /query /status 2>&1 | Out-String; $queried = $LASTEXITCODE -eq 0`,
+		`$source = ([regex]::Match($status, 'Source:\s*(\S+)')).Groups[1].Value -replace ',0x.*', ''`,
+		`$synchronized = if ($queried -and $source -and $status -notmatch 'Local CMOS Clock|Free-running System Clock') { "yes" } else { "no" }`,
+		`$offset = $null`,
+		`if ($synchronized -eq "yes") { $sample = w32tm /stripchart /computer:$source /samples:1 /dataonly 2>&1 | Out-String; $match = [regex]::Match($sample, ', ([+-]?[0-9.]+)s'); if ($match.Success) { $offset = [math]::Abs([double]$match.Groups[1].Value) } }`,
+		`Write-Output "daemon=w32time"; Write-Output "synchronized=$synchronized"; Write-Output "offset_seconds=$offset"`,
+		`if ($synchronized -ne "yes") { Fail "clock is not synchronized by w32time" }`,
+		fmt.Sprintf(`if ($null -eq $offset) { Fail "offset is not reported by w32time" } elseif ($offset -gt %s) { Fail "offset is" $offset "seconds expected at most %s" }`,
+			maxOffset, maxOffset),
+	)
+}
+
+// WithWindows returns a copy of the TimeSyncTestCase that checks w32time on windows instances via PowerShell.
+func (tstc TimeSyncTestCase) WithWindows() TimeSyncTestCase {
+	tstc.windows = true
+	return tstc
+}
+
+// Offsets returns the absolute offset of the clock from the time source on each instance in result, keyed by instance id.
+// Instances that did not report an offset are not included.
+func (tstc TimeSyncTestCase) Offsets(result TestResult) map[string]time.Duration {
+	offsets := map[string]time.Duration{}
+	for _, v := range result.Invocations {
+		seconds, err := strconv.ParseFloat(v.Values()["offset_seconds"], 64)
+		if err != nil {
+			continue
+		}
+		offsets[v.InstanceId] = time.Duration(seconds * float64(time.Second))
+	}
+	return offsets
+}
+
+// NewTimeSyncTestCase is a constructor for TimeSyncTestCase type.
+// maxOffset is the maximum offset of the clock of the instances from their time source eg. 100*time.Millisecond.
+func NewTimeSyncTestCase(maxOffset time.Duration) TimeSyncTestCase {
+	return TimeSyncTestCase{
+		maxOffset: maxOffset,
+	}
+}
@@ -0,0 +1,51 @@
+package tester
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimeSyncTestCase(t *testing.T) {
+	cases := []struct {
+		caseName             string
+		timeSyncTestCase     TimeSyncTestCase
+		expectedDocumentName string
+		expectedAssertion    string
+	}{
+		{
+			caseName:             "Should check the offset on linux instances",
+			timeSyncTestCase:     NewTimeSyncTestCase(100 * time.Millisecond),
+			expectedDocumentName: "AWS-RunShellScript",
+			expectedAssertion:    `if [ -z "$offset" ]; then fail 'offset is not reported by' "$daemon"; elif ! awk -v o="$offset" 'BEGIN { exit !(o <= 0.1) }'; then fail 'offset is' "$offset" 'seconds expected at most 0.1'; fi`,
+		},
+		{
+			caseName:             "Should check the offset on windows instances",
+			timeSyncTestCase:     NewTimeSyncTestCase(2 * time.Second).WithWindows(),
+			expectedDocumentName: "AWS-RunPowerShellScript",
+			expectedAssertion:    `if ($null -eq $offset) { Fail "offset is not reported by w32time" } elseif ($offset -gt 2) { Fail "offset is" $offset "seconds expected at most 2" }`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if e, a := c.expectedDocumentName, c.timeSyncTestCase.documentName(); e != a {
+				t.Errorf("Expected DocumentName to be %s, got %s", e, a)
+			}
+			assertCommandsContain(t, c.timeSyncTestCase.buildCommandParameters()["commands"], c.expectedAssertion)
+		})
+	}
+}
+
+func TestTimeSyncTestCaseOffsets(t *testing.T) {
+	result := TestResult{
+		Invocations: []InvocationResult{
+			{InstanceId: "i-1", Status: types.CommandInvocationStatusSuccess, Output: "daemon=chrony\nsynchronized=yes\noffset_seconds=0.0125\n"},
+			{InstanceId: "i-2", Status: types.CommandInvocationStatusFailed, Output: "daemon=timesyncd\nsynchronized=no\noffset_seconds=\n"},
+		},
+	}
+	expected := map[string]time.Duration{"i-1": 12500 * time.Microsecond}
+	if a := NewTimeSyncTestCase(time.Second).Offsets(result); !reflect.DeepEqual(expected, a) {
+		t.Errorf("Expected %v, but got %v", expected, a)
+	}
+}
@@ -0,0 +1,88 @@
+package tester
+
+import (
+	"fmt"
+	"net"
+)
+
+// TlsTestCase configuration for a test that opens a TLS connection from the target instances to endpoint:port and asserts
+// on the certificate presented by the server.
+// The test fails on an instance if the certificate chain is not trusted by the trust store of the instance, the certificate
+// subject/SAN does not match the server name, the certificate expires within the minimum number of days or the connection
+// did not negotiate the expected protocol version.
+//
+// Important Note - the test relies on the openssl binary being installed on the target instances.
+type TlsTestCase struct {
+	runShellScript
+	endpoint           string // the endpoint to open the tls connection to
+	port               string // the port to open the tls connection to
+	serverName         string // the server name to send as SNI, the certificate subject/SAN should match it
+	minDaysUntilExpiry int    // the minimum number of days the certificate should still be valid for
+	protocolVersion    string // the protocol version the connection should negotiate eg. TLSv1.2, any version if empty
+	timeoutInSeconds   int    // the time allowed to complete the tls handshake
+}
+
+func (ttc TlsTestCase) buildCommandParameters() map[string][]string {
+	connect := fmt.Sprintf("%s:%s", ttc.endpoint, ttc.port)
+	// certificates for ip addresses have them in IP SANs, which -checkhost does not match
+	check := "-checkhost"
+	if net.ParseIP(ttc.serverName) != nil {
+		check = "-checkip"
+	}
+	assertions := []string{
+		fmt.Sprintf("out=$(echo | timeout %d openssl s_client -connect %s -servername %s 2>&1)",
+			ttc.timeoutInSeconds, shellQuote(connect), shellQuote(ttc.serverName)),
+		`cert=$(echo "$out" | openssl x509 2>/dev/null)`,
+		fmt.Sprintf(`if [ -z "$cert" ]; then echo "FAIL: no certificate presented by "%s; exit 1; fi`, shellQuote(connect)),
+		`verify=$(echo "$out" | grep -a 'Verify return code:' | head -1)`,
+		`echo "$verify" | grep -q 'Verify return code: 0 (ok)' || fail 'certificate chain is not trusted by the instance -' "$verify"`,
+		fmt.Sprintf(`echo "$cert" | openssl x509 -noout %s %s | grep -q 'does match' || fail 'certificate subject/SAN does not match' %s`,
+			check, shellQuote(ttc.serverName), shellQuote(ttc.serverName)),
+		fmt.Sprintf(`echo "$cert" | openssl x509 -noout -checkend %d >/dev/null || fail 'certificate expires within %d days -' "$(echo "$cert" | openssl x509 -noout -enddate)"`,
+			ttc.minDaysUntilExpiry*24*60*60, ttc.minDaysUntilExpiry),
+	}
+	if ttc.protocolVersion != "" {
+		assertions = append(assertions,
+			`protocol=$(echo "$out" | sed -n -e 's/^New, \(TLSv[0-9.]*\), Cipher.*/\1/p' -e 's/^ *Protocol *: *//p' | head -1)`,
+			fmt.Sprintf(`[ "$protocol" = %s ] || fail 'negotiated protocol' "$protocol" 'expected' %s`,
+				shellQuote(ttc.protocolVersion), shellQuote(ttc.protocolVersion)),
+		)
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// WithServerName returns a copy of the TlsTestCase that sends serverName as the SNI instead of the endpoint,
+// and expects the certificate subject/SAN to match serverName. An ip address is matched against the IP SANs.
+func (ttc TlsTestCase) WithServerName(serverName string) TlsTestCase {
+	ttc.serverName = serverName
+	return ttc
+}
+
+// WithMinDaysUntilExpiry returns a copy of the TlsTestCase that fails if the certificate expires in less than days.
+func (ttc TlsTestCase) WithMinDaysUntilExpiry(days int) TlsTestCase {
+	ttc.minDaysUntilExpiry = days
+	return ttc
+}
+
+// WithProtocolVersion returns a copy of the TlsTestCase that fails if the connection does not negotiate version.
+// version should be formatted as reported by openssl, eg. "TLSv1.2" or "TLSv1.3".
+func (ttc TlsTestCase) WithProtocolVersion(version string) TlsTestCase {
+	ttc.protocolVersion = version
+	return ttc
+}
+
+// NewTlsTestCase is a constructor for TlsTestCase type.
+// endpoint and port should be the network endpoint to open the TLS connection to. By default the endpoint is sent as
+// the SNI and is expected to match the certificate subject/SAN, the certificate is expected to not have expired and
+// any protocol version is accepted.
+//
+// Certificate chains are validated against the default trust store of the instance, so the test may be used to validate
+// that instances trust a private CA, eg. an ACM Private CA chain.
+func NewTlsTestCase(endpoint string, port string) TlsTestCase {
+	return TlsTestCase{
+		endpoint:         endpoint,
+		port:             port,
+		serverName:       endpoint,
+		timeoutInSeconds: 5,
+	}
+}
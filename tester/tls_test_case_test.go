@@ -0,0 +1,84 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTlsTestCase(t *testing.T) {
+	cases := []struct {
+		caseName                  string
+		tlsTestCase               TlsTestCase
+		expectedCommandParameters map[string][]string
+	}{
+		{
+			caseName:    "Should build default assertions with endpoint as server name",
+			tlsTestCase: NewTlsTestCase("db.internal", "5432"),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`out=$(echo | timeout 5 openssl s_client -connect 'db.internal:5432' -servername 'db.internal' 2>&1)`,
+					`cert=$(echo "$out" | openssl x509 2>/dev/null)`,
+					`if [ -z "$cert" ]; then echo "FAIL: no certificate presented by "'db.internal:5432'; exit 1; fi`,
+					`verify=$(echo "$out" | grep -a 'Verify return code:' | head -1)`,
+					`echo "$verify" | grep -q 'Verify return code: 0 (ok)' || fail 'certificate chain is not trusted by the instance -' "$verify"`,
+					`echo "$cert" | openssl x509 -noout -checkhost 'db.internal' | grep -q 'does match' || fail 'certificate subject/SAN does not match' 'db.internal'`,
+					`echo "$cert" | openssl x509 -noout -checkend 0 >/dev/null || fail 'certificate expires within 0 days -' "$(echo "$cert" | openssl x509 -noout -enddate)"`,
+					"exit $rc",
+				},
+			},
+		},
+		{
+			caseName: "Should build assertions for server name, expiry and protocol version",
+			tlsTestCase: NewTlsTestCase("10.0.0.10", "443").
+				WithServerName("api.internal").
+				WithMinDaysUntilExpiry(30).
+				WithProtocolVersion("TLSv1.3"),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`out=$(echo | timeout 5 openssl s_client -connect '10.0.0.10:443' -servername 'api.internal' 2>&1)`,
+					`cert=$(echo "$out" | openssl x509 2>/dev/null)`,
+					`if [ -z "$cert" ]; then echo "FAIL: no certificate presented by "'10.0.0.10:443'; exit 1; fi`,
+					`verify=$(echo "$out" | grep -a 'Verify return code:' | head -1)`,
+					`echo "$verify" | grep -q 'Verify return code: 0 (ok)' || fail 'certificate chain is not trusted by the instance -' "$verify"`,
+					`echo "$cert" | openssl x509 -noout -checkhost 'api.internal' | grep -q 'does match' || fail 'certificate subject/SAN does not match' 'api.internal'`,
+					`echo "$cert" | openssl x509 -noout -checkend 2592000 >/dev/null || fail 'certificate expires within 30 days -' "$(echo "$cert" | openssl x509 -noout -enddate)"`,
+					`protocol=$(echo "$out" | sed -n -e 's/^New, \(TLSv[0-9.]*\), Cipher.*/\1/p' -e 's/^ *Protocol *: *//p' | head -1)`,
+					`[ "$protocol" = 'TLSv1.3' ] || fail 'negotiated protocol' "$protocol" 'expected' 'TLSv1.3'`,
+					"exit $rc",
+				},
+			},
+		},
+		{
+			caseName:    "Should check the certificate IP SANs of an ip address endpoint",
+			tlsTestCase: NewTlsTestCase("10.0.0.10", "443"),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`out=$(echo | timeout 5 openssl s_client -connect '10.0.0.10:443' -servername '10.0.0.10' 2>&1)`,
+					`cert=$(echo "$out" | openssl x509 2>/dev/null)`,
+					`if [ -z "$cert" ]; then echo "FAIL: no certificate presented by "'10.0.0.10:443'; exit 1; fi`,
+					`verify=$(echo "$out" | grep -a 'Verify return code:' | head -1)`,
+					`echo "$verify" | grep -q 'Verify return code: 0 (ok)' || fail 'certificate chain is not trusted by the instance -' "$verify"`,
+					`echo "$cert" | openssl x509 -noout -checkip '10.0.0.10' | grep -q 'does match' || fail 'certificate subject/SAN does not match' '10.0.0.10'`,
+					`echo "$cert" | openssl x509 -noout -checkend 0 >/dev/null || fail 'certificate expires within 0 days -' "$(echo "$cert" | openssl x509 -noout -enddate)"`,
+					"exit $rc",
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if e, a := "AWS-RunShellScript", c.tlsTestCase.documentName(); e != a {
+				t.Errorf("Expected DocumentName to be %s, got %s", e, a)
+			}
+			if e, a := c.expectedCommandParameters, c.tlsTestCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package tester
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// UdpTestCase configuration for a test that sends a UDP datagram with a payload from the target instances to endpoint:port
+// and checks for the response.
+// By default the test passes on an instance if any response is received within the timeout. The response may be checked
+// against a pattern with WithResponsePattern, or the test may be configured to not expect any response at all for
+// fire-and-forget protocols like syslog with WithNoResponseExpected.
+//
+// Like TcpConnectionTestWithTagName, the test relies on native bash capability (/dev/udp) to minimise the dependency
+// on installed binaries.
+type UdpTestCase struct {
+	runShellScript
+	endpoint           string // the endpoint to send the datagram to
+	port               string // the port to send the datagram to
+	payload            []byte // the payload of the datagram
+	responsePattern    string // extended regular expression the hex encoded response should match, any response if empty
+	noResponseExpected bool   // pass as long as the datagram is sent without an ICMP unreachable response
+	timeoutInSeconds   int    // the time to wait for a response
+}
+
+func (utc UdpTestCase) buildCommandParameters() map[string][]string {
+	target := fmt.Sprintf("%s:%s", utc.endpoint, utc.port)
+	send := fmt.Sprintf("exec 3<>/dev/udp/%s/%s; printf '%s' >&3", shellQuote(utc.endpoint), shellQuote(utc.port),
+		octalEscape(utc.payload))
+	if utc.noResponseExpected {
+		// timing out while waiting on a response is a pass, a read error means the port was reported unreachable
+		return shellCommandParameters(assertionScript(
+			fmt.Sprintf("timeout %d bash -c %s", utc.timeoutInSeconds, shellQuote(send+"; dd bs=65535 count=1 <&3 >/dev/null 2>&1")),
+			`status=$?`,
+			fmt.Sprintf(`[ $status -eq 0 ] || [ $status -eq 124 ] || fail 'datagram to' %s 'was rejected, exit code' "$status"`, shellQuote(target)),
+		))
+	}
+	assertions := []string{
+		fmt.Sprintf("response=$(timeout %d bash -c %s | od -An -v -tx1 | tr -d ' \\n')",
+			utc.timeoutInSeconds, shellQuote(send+"; dd bs=65535 count=1 <&3 2>/dev/null")),
+		`echo "response=$response"`,
+		fmt.Sprintf(`if [ -z "$response" ]; then echo "FAIL: no response from "%s" within %d seconds"; exit 1; fi`,
+			shellQuote(target), utc.timeoutInSeconds),
+	}
+	if utc.responsePattern != "" {
+		assertions = append(assertions,
+			fmt.Sprintf(`echo "$response" | grep -Eq %s || fail 'response does not match' %s`,
+				shellQuote(utc.responsePattern), shellQuote(utc.responsePattern)))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+// WithResponsePattern returns a copy of the UdpTestCase that fails if the response does not match pattern.
+// pattern is an extended regular expression that is matched against the lowercase hex encoding of the response,
+// eg. "^1b" for a response that starts with the byte 0x1b.
+func (utc UdpTestCase) WithResponsePattern(pattern string) UdpTestCase {
+	utc.responsePattern = pattern
+	return utc
+}
+
+// WithNoResponseExpected returns a copy of the UdpTestCase that passes if no response is received within the timeout.
+// It still fails if the instance receives an ICMP port unreachable for the datagram.
+// This is useful for protocols that do not respond, eg. syslog.
+func (utc UdpTestCase) WithNoResponseExpected() UdpTestCase {
+	utc.noResponseExpected = true
+	return utc
+}
+
+// WithTimeout returns a copy of the UdpTestCase that waits timeoutInSeconds for a response.
+func (utc UdpTestCase) WithTimeout(timeoutInSeconds int) UdpTestCase {
+	utc.timeoutInSeconds = timeoutInSeconds
+	return utc
+}
+
+// NewUdpTestCase is a constructor for UdpTestCase type.
+// endpoint and port should be the network endpoint to send the datagram to, and payload the content of the datagram.
+// By default the test waits 3 seconds for any response.
+func NewUdpTestCase(endpoint string, port string, payload []byte) UdpTestCase {
+	return UdpTestCase{
+		endpoint:         endpoint,
+		port:             port,
+		payload:          payload,
+		timeoutInSeconds: 3,
+	}
+}
+
+// NewDnsUdpTestCase returns a UdpTestCase that sends a DNS query for the A record of name to the resolver on port 53,
+// and expects a DNS response for the query.
+func NewDnsUdpTestCase(resolver string, name string) UdpTestCase {
+	return NewUdpTestCase(resolver, "53", DnsQueryPayload(name)).WithResponsePattern(fmt.Sprintf("^%04x[89a-f]", dnsQueryId))
+}
+
+// NewNtpUdpTestCase returns a UdpTestCase that sends an NTP client request to the server on port 123,
+// and expects an NTP server response.
+func NewNtpUdpTestCase(server string) UdpTestCase {
+	// the first byte of a server response has mode 4 in its lowest 3 bits
+	return NewUdpTestCase(server, "123", NtpRequestPayload()).WithResponsePattern("^[0-9a-f][4c]")
+}
+
+const dnsQueryId = 0x5353
+
+// DnsQueryPayload returns the payload of a recursive DNS query for the A record of name.
+func DnsQueryPayload(name string) []byte {
+	header := make([]byte, 12)
+	binary.BigEndian.PutUint16(header[0:], dnsQueryId)
+	binary.BigEndian.PutUint16(header[2:], 0x0100) // recursion desired
+	binary.BigEndian.PutUint16(header[4:], 1)      // one question
+	payload := header
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		payload = append(payload, byte(len(label)))
+		payload = append(payload, label...)
+	}
+	// root label, followed by type A and class IN
+	return append(payload, 0, 0, 1, 0, 1)
+}
+
+// NtpRequestPayload returns the payload of an NTP version 3 client request.
+func NtpRequestPayload() []byte {
+	payload := make([]byte, 48)
+	payload[0] = 0x1b // leap indicator 0, version 3, mode 3 (client)
+	return payload
+}
+
+// octalEscape encodes b as octal escapes understood by printf.
+func octalEscape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		fmt.Fprintf(&sb, `\%03o`, c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUdpTestCase(t *testing.T) {
+	cases := []struct {
+		caseName                  string
+		udpTestCase               UdpTestCase
+		expectedCommandParameters map[string][]string
+	}{
+		{
+			caseName:    "Should build assertions for a response matching the pattern",
+			udpTestCase: NewUdpTestCase("10.0.0.2", "123", []byte{0x1b, 0}).WithResponsePattern("^1c"),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`response=$(timeout 3 bash -c 'exec 3<>/dev/udp/'"'"'10.0.0.2'"'"'/'"'"'123'"'"'; printf '"'"'\033\000'"'"' >&3; dd bs=65535 count=1 <&3 2>/dev/null' | od -An -v -tx1 | tr -d ' \n')`,
+					`echo "response=$response"`,
+					`if [ -z "$response" ]; then echo "FAIL: no response from "'10.0.0.2:123'" within 3 seconds"; exit 1; fi`,
+					`echo "$response" | grep -Eq '^1c' || fail 'response does not match' '^1c'`,
+					"exit $rc",
+				},
+			},
+		},
+		{
+			caseName:    "Should build assertions for no response expected",
+			udpTestCase: NewUdpTestCase("syslog.internal", "514", []byte("<14>test")).WithNoResponseExpected().WithTimeout(2),
+			expectedCommandParameters: map[string][]string{
+				"commands": {
+					"rc=0",
+					`fail() { echo "FAIL: $*"; rc=1; }`,
+					`timeout 2 bash -c 'exec 3<>/dev/udp/'"'"'syslog.internal'"'"'/'"'"'514'"'"'; printf '"'"'\074\061\064\076\164\145\163\164'"'"' >&3; dd bs=65535 count=1 <&3 >/dev/null 2>&1'`,
+					`status=$?`,
+					`[ $status -eq 0 ] || [ $status -eq 124 ] || fail 'datagram to' 'syslog.internal:514' 'was rejected, exit code' "$status"`,
+					"exit $rc",
+				},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.caseName, func(t *testing.T) {
+			if e, a := c.expectedCommandParameters, c.udpTestCase.buildCommandParameters(); !reflect.DeepEqual(e, a) {
+				t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+			}
+		})
+	}
+}
+
+func TestDnsQueryPayload(t *testing.T) {
+	expected := []byte{
+		0x53, 0x53, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		0x00, 0x01, 0x00, 0x01,
+	}
+	if a := DnsQueryPayload("example.com."); !reflect.DeepEqual(expected, a) {
+		t.Errorf("Expected %v, but got %v", expected, a)
+	}
+}
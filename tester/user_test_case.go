@@ -0,0 +1,134 @@
+package tester
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// UserTestCase configuration for a test that checks a local user on the target instances exists, or is absent, and its
+// uid, primary gid, login shell, group membership and sudo rights, eg. to prove baseline hardening like no sudo for
+// ec2-user in production.
+// Users are looked up with getent, so users from any configured name service are found. The actual state of the user is
+// reported as name=value pairs in the output, available with InvocationResult.Values.
+type UserTestCase struct {
+	runShellScript
+	name             string   // the name of the user
+	absent           bool     // check that the user does not exist
+	uid              int      // the expected uid, not checked if negative
+	gid              int      // the expected primary gid, not checked if negative
+	shell            string   // the expected login shell, not checked if empty
+	groups           []string // the groups the user should be a member of
+	sudo             *bool    // whether the user should be allowed to run commands with sudo, not checked if nil
+	passwordlessSudo *bool    // whether the user should be allowed to run commands with sudo without a password, not checked if nil
+}
+
+// sudoAllRulePattern matches the rules listed by sudo -l that allow any command, with any tags eg. SETENV:, and
+// passwordlessSudoAllRulePattern those that also have the NOPASSWD: tag.
+const (
+	sudoAllRulePattern             = `^[[:space:]]+\([^)]*\)[[:space:]]+([A-Z_]+:[[:space:]]*)*ALL$`
+	passwordlessSudoAllRulePattern = `^[[:space:]]+\([^)]*\)[[:space:]]+([A-Z_]+:[[:space:]]*)*NOPASSWD:[[:space:]]*([A-Z_]+:[[:space:]]*)*ALL$`
+)
+
+func (utc UserTestCase) buildCommandParameters() map[string][]string {
+	name := shellQuote(utc.name)
+	if utc.absent {
+		return shellCommandParameters(assertionScript(
+			fmt.Sprintf(`[ -z "$(getent passwd %s)" ] || fail 'user' %s 'exists'`, name, name),
+		))
+	}
+	assertions := []string{
+		fmt.Sprintf(`entry=$(getent passwd %s)`, name),
+		fmt.Sprintf(`if [ -z "$entry" ]; then echo "FAIL: user "%s" does not exist"; exit 1; fi`, name),
+		`uid=$(echo "$entry" | cut -d : -f 3); gid=$(echo "$entry" | cut -d : -f 4); shell=$(echo "$entry" | cut -d : -f 7)`,
+		fmt.Sprintf(`groups=$(id -Gn %s | tr ' ' ',')`, name),
+		fmt.Sprintf(`sudo_rights=$(sudo -n -l -U %s 2>/dev/null); sudo=no; passwordless_sudo=no`, name),
+		// only rules for ALL commands count, eg. (ALL : ALL) ALL or (ALL) NOPASSWD: ALL, not rules for specific commands
+		fmt.Sprintf(`echo "$sudo_rights" | grep -Eq %s && sudo=yes`, shellQuote(sudoAllRulePattern)),
+		fmt.Sprintf(`echo "$sudo_rights" | grep -Eq %s && passwordless_sudo=yes`, shellQuote(passwordlessSudoAllRulePattern)),
+		`echo "uid=$uid"; echo "gid=$gid"; echo "shell=$shell"; echo "groups=$groups"; echo "sudo=$sudo"; echo "passwordless_sudo=$passwordless_sudo"`,
+	}
+	if utc.uid >= 0 {
+		assertions = append(assertions, expectEqual("uid", "$uid", strconv.Itoa(utc.uid)))
+	}
+	if utc.gid >= 0 {
+		assertions = append(assertions, expectEqual("gid", "$gid", strconv.Itoa(utc.gid)))
+	}
+	if utc.shell != "" {
+		assertions = append(assertions, expectEqual("shell", "$shell", utc.shell))
+	}
+	for _, v := range utc.groups {
+		assertions = append(assertions, fmt.Sprintf(`case ",$groups," in *,%s,*) ;; *) fail 'user is not a member of group' %s '- groups are' "$groups" ;; esac`,
+			shellQuote(v), shellQuote(v)))
+	}
+	if utc.sudo != nil {
+		assertions = append(assertions, expectEqual("sudo", "$sudo", yesNo(*utc.sudo)))
+	}
+	if utc.passwordlessSudo != nil {
+		assertions = append(assertions, expectEqual("passwordless sudo", "$passwordless_sudo", yesNo(*utc.passwordlessSudo)))
+	}
+	return shellCommandParameters(assertionScript(assertions...))
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// WithUid returns a copy of the UserTestCase that checks the uid of the user.
+func (utc UserTestCase) WithUid(uid int) UserTestCase {
+	utc.uid = uid
+	return utc
+}
+
+// WithGid returns a copy of the UserTestCase that checks the gid of the primary group of the user.
+func (utc UserTestCase) WithGid(gid int) UserTestCase {
+	utc.gid = gid
+	return utc
+}
+
+// WithShell returns a copy of the UserTestCase that checks the login shell of the user eg. "/sbin/nologin".
+func (utc UserTestCase) WithShell(shell string) UserTestCase {
+	utc.shell = shell
+	return utc
+}
+
+// WithGroups returns a copy of the UserTestCase that checks the user is a member of each of groups, either as its
+// primary group or a supplementary group.
+func (utc UserTestCase) WithGroups(groups ...string) UserTestCase {
+	utc.groups = groups
+	return utc
+}
+
+// WithSudo returns a copy of the UserTestCase that checks whether the user is allowed to run any command with sudo, ie.
+// has a sudoers rule for ALL commands. Rules for specific commands are not counted.
+func (utc UserTestCase) WithSudo(sudo bool) UserTestCase {
+	utc.sudo = &sudo
+	return utc
+}
+
+// WithPasswordlessSudo returns a copy of the UserTestCase that checks whether the user is allowed to run any command
+// with sudo without a password, ie. has a NOPASSWD rule for ALL commands.
+func (utc UserTestCase) WithPasswordlessSudo(passwordlessSudo bool) UserTestCase {
+	utc.passwordlessSudo = &passwordlessSudo
+	return utc
+}
+
+// NewUserTestCase is a constructor for UserTestCase type.
+// name should be the name of the user on the target instances. By default the test only checks that the user exists,
+// further checks are added with the With methods.
+func NewUserTestCase(name string) UserTestCase {
+	return UserTestCase{
+		name: name,
+		uid:  -1,
+		gid:  -1,
+	}
+}
+
+// NewAbsentUserTestCase returns a UserTestCase that checks that the user name does not exist on the target instances.
+func NewAbsentUserTestCase(name string) UserTestCase {
+	utc := NewUserTestCase(name)
+	utc.absent = true
+	return utc
+}
@@ -0,0 +1,53 @@
+package tester
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUserTestCase(t *testing.T) {
+	testCase := NewUserTestCase("ec2-user").
+		WithUid(1000).
+		WithGid(1000).
+		WithShell("/bin/bash").
+		WithGroups("adm", "wheel").
+		WithPasswordlessSudo(false)
+	commands := testCase.buildCommandParameters()["commands"]
+	assertCommandsContain(t, commands,
+		`entry=$(getent passwd 'ec2-user')`,
+		`if [ -z "$entry" ]; then echo "FAIL: user "'ec2-user'" does not exist"; exit 1; fi`,
+		`groups=$(id -Gn 'ec2-user' | tr ' ' ',')`,
+		`[ "$uid" = '1000' ] || fail 'uid is' "$uid" 'expected' '1000'`,
+		`[ "$gid" = '1000' ] || fail 'gid is' "$gid" 'expected' '1000'`,
+		`[ "$shell" = '/bin/bash' ] || fail 'shell is' "$shell" 'expected' '/bin/bash'`,
+		`case ",$groups," in *,'adm',*) ;; *) fail 'user is not a member of group' 'adm' '- groups are' "$groups" ;; esac`,
+		`case ",$groups," in *,'wheel',*) ;; *) fail 'user is not a member of group' 'wheel' '- groups are' "$groups" ;; esac`,
+		`echo "$sudo_rights" | grep -Eq '^[[:space:]]+\([^)]*\)[[:space:]]+([A-Z_]+:[[:space:]]*)*ALL$' && sudo=yes`,
+		`echo "$sudo_rights" | grep -Eq '^[[:space:]]+\([^)]*\)[[:space:]]+([A-Z_]+:[[:space:]]*)*NOPASSWD:[[:space:]]*([A-Z_]+:[[:space:]]*)*ALL$' && passwordless_sudo=yes`,
+		`[ "$passwordless_sudo" = 'no' ] || fail 'passwordless sudo is' "$passwordless_sudo" 'expected' 'no'`,
+	)
+}
+
+func TestUserTestCaseDefaults(t *testing.T) {
+	commands := NewUserTestCase("app").buildCommandParameters()["commands"]
+	if e, a := 11, len(commands); e != a {
+		t.Errorf("Expected %d commands with only existence checked, but got %d: %v", e, a, commands)
+	}
+	assertCommandsContain(t, NewUserTestCase("app").WithSudo(true).buildCommandParameters()["commands"],
+		`[ "$sudo" = 'yes' ] || fail 'sudo is' "$sudo" 'expected' 'yes'`,
+	)
+}
+
+func TestAbsentUserTestCase(t *testing.T) {
+	expectedCommandParameters := map[string][]string{
+		"commands": {
+			"rc=0",
+			`fail() { echo "FAIL: $*"; rc=1; }`,
+			`[ -z "$(getent passwd 'games')" ] || fail 'user' 'games' 'exists'`,
+			"exit $rc",
+		},
+	}
+	if e, a := expectedCommandParameters, NewAbsentUserTestCase("games").buildCommandParameters(); !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected the command parameters to be \n%v, but got \n%v", e, a)
+	}
+}